@@ -19,6 +19,7 @@ package yashiro
 import (
 	"github.com/dwango/yashiro/pkg/config"
 	"github.com/dwango/yashiro/pkg/engine"
+	"github.com/dwango/yashiro/pkg/provider"
 )
 
 // Engine initializes external store client and template.
@@ -27,10 +28,25 @@ type Engine = engine.Engine
 // Config is the configuration for this library.
 type Config = config.Config
 
+// Provider is an external store which provides values.
+type Provider = provider.Provider
+
+// SelectiveProvider is a Provider which can get only values referenced by a template.
+type SelectiveProvider = provider.SelectiveProvider
+
 var (
 	// NewEngine returns a new Engine.
 	NewEngine = engine.New
 
 	// IgnoreNotFound is an option to ignore missing external store values.
 	IgnoreNotFound = engine.IgnoreNotFound
+
+	// DynamicLookup is an option to enable template functions which get values at render time.
+	DynamicLookup = engine.DynamicLookup
+
+	// Helper is an option to add a helper template which defines named templates.
+	Helper = engine.Helper
+
+	// RegisterProvider registers an external store provider.
+	RegisterProvider = provider.Register
 )
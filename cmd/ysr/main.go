@@ -23,6 +23,14 @@ import (
 	"os/signal"
 
 	"github.com/dwango/yashiro/internal/cmd"
+
+	// Built-in providers other than AWS register themselves.
+	_ "github.com/dwango/yashiro/pkg/provider/azure"
+	_ "github.com/dwango/yashiro/pkg/provider/file"
+	_ "github.com/dwango/yashiro/pkg/provider/gcp"
+	_ "github.com/dwango/yashiro/pkg/provider/kubernetes"
+	_ "github.com/dwango/yashiro/pkg/provider/sops"
+	_ "github.com/dwango/yashiro/pkg/provider/vault"
 )
 
 func main() {
@@ -18,104 +18,701 @@ package client
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	secs "github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	secsTypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	ssmTypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/dwango/yashiro/internal/client/cache"
 	"github.com/dwango/yashiro/internal/values"
 	"github.com/dwango/yashiro/pkg/config"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// ssmGetParametersMaxNames is the maximum number of names in a GetParameters request.
+	ssmGetParametersMaxNames = 10
+	// secsBatchGetSecretValueMaxIDs is the maximum number of secret IDs in a BatchGetSecretValue
+	// request.
+	secsBatchGetSecretValueMaxIDs = 20
+	// secsErrorCodeNotFound is an error code of BatchGetSecretValue for a secret not found.
+	secsErrorCodeNotFound = "ResourceNotFoundException"
+	// secsBinaryCacheKeySuffix is appended to the cache key of a binary secret. Binary secrets
+	// are cached as base64 strings. "#" is not allowed in secret names and ARNs.
+	secsBinaryCacheKeySuffix = "#binary"
 )
 
 type awsClient struct {
-	ssmClient           ssmClient
-	secsClient          secsClient
+	clients             map[config.AwsSourceConfig]awsServiceClients
 	parameterStoreValue []config.AwsParameterStoreValueConfig
-	secretsManagerValue []config.ValueConfig
+	secretsManagerValue []config.AwsSecretsManagerValueConfig
+	maxConcurrency      int // maximum number of concurrent requests, DefaultAwsMaxConcurrency if not configured
 }
 
 func newAwsClient(cfg *config.Config) (Client, error) {
+	if cfg.Aws == nil {
+		return nil, nil
+	}
 	if cfg.Aws.SdkConfig == nil {
 		return nil, fmt.Errorf("require aws sdk config")
 	}
+	for _, v := range cfg.Aws.ParameterStoreValues {
+		if v.Version != 0 && len(v.Label) != 0 {
+			return nil, fmt.Errorf("both version and label are set: name='%s'", v.Name)
+		}
+	}
+	for _, v := range cfg.Aws.SecretsManagerValues {
+		switch v.BinaryEncoding {
+		case config.AwsSecretBinaryEncodingUnspecified, config.AwsSecretBinaryEncodingBase64, config.AwsSecretBinaryEncodingRaw:
+		default:
+			return nil, fmt.Errorf("invalid binary encoding: name='%s', encoding='%s'", v.Name, v.BinaryEncoding)
+		}
+	}
 
-	var cc cache.Cache
-	if cfg.Global.EnableCache {
-		// get AWS account ID
-		accountID, err := getAwsAccountId(cfg.Aws.SdkConfig)
-		if err != nil {
-			return nil, err
+	// The default source is always used, e.g. by values without a source.
+	sources := []config.AwsSourceConfig{{}}
+	for _, v := range cfg.Aws.ParameterStoreValues {
+		sources = append(sources, v.AwsSourceConfig)
+	}
+	for _, v := range cfg.Aws.SecretsManagerValues {
+		sources = append(sources, v.AwsSourceConfig)
+	}
+
+	clients := make(map[config.AwsSourceConfig]awsServiceClients)
+	for _, source := range sources {
+		if _, ok := clients[source]; ok {
+			continue
 		}
-		cc, err = cache.New(cfg.Global.Cache, cache.WithCacheKeys("aws", cfg.Aws.SdkConfig.Region, accountID))
+
+		sdkConfig, err := awsSourceSdkConfig(context.Background(), cfg.Aws.SdkConfig, cfg.Aws.Endpoints, source)
 		if err != nil {
 			return nil, err
 		}
+		clients[source] = newAwsServiceClients(cfg, sdkConfig, source)
+	}
+
+	maxConcurrency := cfg.Aws.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = config.DefaultAwsMaxConcurrency
 	}
 
 	return &awsClient{
+		clients:             clients,
+		parameterStoreValue: cfg.Aws.ParameterStoreValues,
+		secretsManagerValue: cfg.Aws.SecretsManagerValues,
+		maxConcurrency:      maxConcurrency,
+	}, nil
+}
+
+// awsServiceClients are clients of AWS services for a source of values.
+type awsServiceClients struct {
+	ssmClient  ssmClient
+	secsClient secsClient
+}
+
+func newAwsServiceClients(cfg *config.Config, sdkConfig aws.Config, source config.AwsSourceConfig) awsServiceClients {
+	endpoints := cfg.Aws.Endpoints
+
+	var cc cache.Cache
+	if cfg.Global.EnableCache {
+		namespace := awsCacheNamespace(cfg.Aws, source)
+		timeout := config.DefaultAwsAccountLookupTimeout
+		if cfg.Aws.AccountLookupTimeout != 0 {
+			timeout = time.Duration(cfg.Aws.AccountLookupTimeout)
+		}
+
+		cc = &awsLazyCache{newCache: func(ctx context.Context) (cache.Cache, error) {
+			if len(namespace) == 0 {
+				accounts, err := cache.New(cfg.Global.Cache, cache.WithCacheKeys("aws", "accounts"))
+				if err != nil {
+					return nil, err
+				}
+				accountID, err := lookupAwsAccountId(ctx, sdkConfig, endpoints, accounts, timeout)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get aws account id for cache namespace: %w", err)
+				}
+				namespace = accountID
+			}
+			return cache.New(cfg.Global.Cache, cache.WithCacheKeys("aws", sdkConfig.Region, namespace))
+		}}
+	}
+
+	return awsServiceClients{
 		ssmClient: &ssmClientWithCache{
-			client: ssm.NewFromConfig(*cfg.Aws.SdkConfig),
-			cache:  cc,
+			client: ssm.NewFromConfig(sdkConfig, func(o *ssm.Options) {
+				if len(endpoints.Ssm) != 0 {
+					o.BaseEndpoint = aws.String(endpoints.Ssm)
+				}
+			}),
+			cache: cc,
 		},
 		secsClient: &secsClientWithCache{
-			client: secs.NewFromConfig(*cfg.Aws.SdkConfig),
-			cache:  cc,
+			client: secs.NewFromConfig(sdkConfig, func(o *secs.Options) {
+				if len(endpoints.SecretsManager) != 0 {
+					o.BaseEndpoint = aws.String(endpoints.SecretsManager)
+				}
+			}),
+			cache: cc,
 		},
-		parameterStoreValue: cfg.Aws.ParameterStoreValues,
-		secretsManagerValue: cfg.Aws.SecretsManagerValues,
-	}, nil
+	}
+}
+
+// awsCacheNamespace returns the cache namespace of the source. If it returns an empty string,
+// the AWS account ID is used as the namespace.
+func awsCacheNamespace(cfg *config.AwsConfig, source config.AwsSourceConfig) string {
+	if len(source.CacheNamespace) != 0 {
+		return source.CacheNamespace
+	}
+	if len(source.RoleArn) != 0 {
+		if roleArn, err := arn.Parse(source.RoleArn); err == nil && len(roleArn.AccountID) != 0 {
+			return roleArn.AccountID
+		}
+	}
+	if len(source.Profile) != 0 {
+		return "profile:" + source.Profile
+	}
+
+	return cfg.CacheNamespace
+}
+
+// awsLazyCache is a cache created on first use, so that the AWS account ID is looked up only if
+// the cache is used, and with the context of the request.
+type awsLazyCache struct {
+	mu       sync.Mutex
+	cache    cache.Cache
+	newCache func(ctx context.Context) (cache.Cache, error)
+}
+
+func (c *awsLazyCache) get(ctx context.Context) (cache.Cache, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// If creating failed, it is retried on next use.
+	if c.cache == nil {
+		cc, err := c.newCache(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.cache = cc
+	}
+
+	return c.cache, nil
+}
+
+// Load implements cache.Cache.
+func (c *awsLazyCache) Load(ctx context.Context, key string, decrypt bool) (*string, bool, error) {
+	cc, err := c.get(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	return cc.Load(ctx, key, decrypt)
+}
+
+// LoadStale implements cache.Cache.
+func (c *awsLazyCache) LoadStale(ctx context.Context, key string, decrypt bool) (*string, error) {
+	cc, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cc.LoadStale(ctx, key, decrypt)
+}
+
+// Save implements cache.Cache.
+func (c *awsLazyCache) Save(ctx context.Context, key string, value *string, encrypt bool) error {
+	cc, err := c.get(ctx)
+	if err != nil {
+		return err
+	}
+	return cc.Save(ctx, key, value, encrypt)
+}
+
+// awsSourceSdkConfig returns AWS SDK configuration for the source based on sdkConfig.
+func awsSourceSdkConfig(ctx context.Context, sdkConfig *aws.Config, endpoints config.AwsEndpointsConfig, source config.AwsSourceConfig) (aws.Config, error) {
+	cfg := sdkConfig.Copy()
+
+	if len(source.Profile) != 0 {
+		var err error
+		cfg, err = awsconfig.LoadDefaultConfig(ctx, awsconfig.WithSharedConfigProfile(source.Profile))
+		if err != nil {
+			return aws.Config{}, fmt.Errorf("failed to load aws profile '%s': %w", source.Profile, err)
+		}
+		if len(cfg.Region) == 0 {
+			cfg.Region = sdkConfig.Region
+		}
+	}
+
+	if len(source.Region) != 0 {
+		cfg.Region = source.Region
+	}
+
+	if len(source.RoleArn) != 0 {
+		provider := stscreds.NewAssumeRoleProvider(newStsClient(cfg, endpoints), source.RoleArn, func(o *stscreds.AssumeRoleOptions) {
+			if len(source.ExternalID) != 0 {
+				o.ExternalID = aws.String(source.ExternalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	return cfg, nil
+}
+
+// awsValue is a value got from AWS. binary is set instead of value for a binary secret. found
+// is false if the value does not exist.
+type awsValue struct {
+	value  *string
+	binary []byte
+	found  bool
+}
+
+// awsBatch is a set of names to get in batch requests. Each name is requested once even if
+// several value configurations refer to it.
+type awsBatch struct {
+	names   []string
+	indexes map[string][]int // indexes of value configurations for each name
+}
+
+func (b *awsBatch) add(name string, index int) {
+	if b.indexes == nil {
+		b.indexes = make(map[string][]int)
+	}
+	if _, ok := b.indexes[name]; !ok {
+		b.names = append(b.names, name)
+	}
+	b.indexes[name] = append(b.indexes[name], index)
 }
 
 func (c awsClient) GetValues(ctx context.Context, ignoreNotFound bool) (values.Values, error) {
-	values := make(values.Values, len(c.parameterStoreValue)+len(c.secretsManagerValue))
+	parameters := make([]awsValue, len(c.parameterStoreValue))
+	parametersByPath := make([][]ssmTypes.Parameter, len(c.parameterStoreValue))
+	secrets := make([]awsValue, len(c.secretsManagerValue))
+
+	// Parameters are requested in batches per source. Parameters with and without decryption
+	// are requested separately, because decryption is specified per request.
+	type ssmBatchKey struct {
+		source     config.AwsSourceConfig
+		decryption bool
+	}
+	ssmBatches := make(map[ssmBatchKey]*awsBatch)
+	for i, v := range c.parameterStoreValue {
+		if len(v.Path) != 0 {
+			continue
+		}
+		key := ssmBatchKey{source: v.AwsSourceConfig, decryption: v.Decryption != nil && *v.Decryption}
+		if _, ok := ssmBatches[key]; !ok {
+			ssmBatches[key] = &awsBatch{}
+		}
+		ssmBatches[key].add(v.GetParameterName(), i)
+	}
+	// Secrets with a version are requested one by one, because BatchGetSecretValue gets only
+	// the current version.
+	secsBatches := make(map[config.AwsSourceConfig]*awsBatch)
+	for i, v := range c.secretsManagerValue {
+		if len(v.VersionStage) != 0 || len(v.VersionID) != 0 {
+			continue
+		}
+		if _, ok := secsBatches[v.AwsSourceConfig]; !ok {
+			secsBatches[v.AwsSourceConfig] = &awsBatch{}
+		}
+		secsBatches[v.AwsSourceConfig].add(v.Name, i)
+	}
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	if c.maxConcurrency > 0 {
+		eg.SetLimit(c.maxConcurrency)
+	}
+
+	for key, batch := range ssmBatches {
+		client := c.clients[key.source].ssmClient
+		for names := range slices.Chunk(batch.names, ssmGetParametersMaxNames) {
+			eg.Go(func() error {
+				output, err := getParameters(egCtx, client, names, key.decryption)
+				if err != nil {
+					return err
+				}
+
+				for name, p := range output {
+					for _, i := range batch.indexes[name] {
+						parameters[i] = p
+					}
+				}
+				return nil
+			})
+		}
+	}
 
-	for _, v := range c.parameterStoreValue {
-		output, err := c.ssmClient.GetParameter(ctx, &ssm.GetParameterInput{
-			Name:           &v.Name,
-			WithDecryption: v.Decryption,
+	for i, v := range c.parameterStoreValue {
+		if len(v.Path) == 0 {
+			continue
+		}
+		client := c.clients[v.AwsSourceConfig].ssmClient
+		eg.Go(func() error {
+			output, err := getParametersByPath(egCtx, client, v)
+			if err != nil {
+				return GettingValueError(v.Path, err)
+			}
+			parametersByPath[i] = output
+			return nil
 		})
+	}
 
-		if err != nil {
-			var notFoundErr *ssmTypes.ParameterNotFound
-			if ignoreNotFound && errors.As(err, &notFoundErr) {
+	for source, batch := range secsBatches {
+		client := c.clients[source].secsClient
+		for ids := range slices.Chunk(batch.names, secsBatchGetSecretValueMaxIDs) {
+			eg.Go(func() error {
+				output, err := batchGetSecretValue(egCtx, client, ids)
+				if err != nil {
+					return err
+				}
+
+				for id, secret := range output {
+					for _, i := range batch.indexes[id] {
+						secrets[i] = secret
+					}
+				}
+				return nil
+			})
+		}
+	}
+
+	for i, v := range c.secretsManagerValue {
+		if len(v.VersionStage) == 0 && len(v.VersionID) == 0 {
+			continue
+		}
+		client := c.clients[v.AwsSourceConfig].secsClient
+		eg.Go(func() error {
+			secret, err := getSecretValue(egCtx, client, &secs.GetSecretValueInput{
+				SecretId:     &v.Name,
+				VersionId:    stringOrNil(v.VersionID),
+				VersionStage: stringOrNil(v.VersionStage),
+			})
+			if err != nil {
+				return err
+			}
+			secrets[i] = secret
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	// Set values in the order of configurations, so that a later value overwrites an earlier one.
+	values := make(values.Values, len(c.parameterStoreValue)+len(c.secretsManagerValue))
+
+	for i, v := range c.parameterStoreValue {
+		if len(v.Path) != 0 {
+			if err := setParametersByPath(values, v, parametersByPath[i], ignoreNotFound); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !parameters[i].found {
+			if ignoreNotFound {
 				continue
 			}
-			return nil, gettingValueError(v.Name, err)
+			return nil, GettingValueError(v.GetParameterName(), &ssmTypes.ParameterNotFound{Message: aws.String("parameter not found")})
 		}
 
-		if err := values.SetValue(v, output.Parameter.Value); err != nil {
+		if err := values.SetValue(v, parameters[i].value); err != nil {
 			return nil, err
 		}
 	}
 
-	for _, v := range c.secretsManagerValue {
-		output, err := c.secsClient.GetSecretValue(ctx, &secs.GetSecretValueInput{
-			SecretId: &v.Name,
-		})
+	for i, v := range c.secretsManagerValue {
+		if !secrets[i].found {
+			if ignoreNotFound {
+				continue
+			}
+			return nil, GettingValueError(v.Name, &secsTypes.ResourceNotFoundException{Message: aws.String("secret not found")})
+		}
+
+		value, err := secretString(v, secrets[i])
+		if err != nil {
+			return nil, GettingValueError(v.Name, err)
+		}
+		if err := values.SetValue(v, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// GetValuesByKeys skips parameters and secrets which are not referenced by keys. Parameters by
+// Path without Ref are always got.
+func (c awsClient) GetValuesByKeys(ctx context.Context, keys []string, ignoreNotFound bool) (values.Values, error) {
+	c.parameterStoreValue = SelectValues(c.parameterStoreValue, keys, awsParameterStoreReferenceName)
+	c.secretsManagerValue = SelectValues(c.secretsManagerValue, keys, ReferenceName)
+
+	return c.GetValues(ctx, ignoreNotFound)
+}
+
+// LookupParameter gets a parameter which is not declared in the configuration by the default
+// source. The parameter is decrypted, and name can have a selector, e.g. "/name:3".
+func (c awsClient) LookupParameter(ctx context.Context, name string) (*string, error) {
+	output, err := c.clients[config.AwsSourceConfig{}].ssmClient.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		var notFoundErr *ssmTypes.ParameterNotFound
+		if errors.As(err, &notFoundErr) {
+			return nil, nil
+		}
+		return nil, GettingValueError(name, err)
+	}
+
+	return output.Parameter.Value, nil
+}
+
+// LookupSecret gets the current version of a secret which is not declared in the configuration
+// by the default source. Binary secrets are encoded as base64.
+func (c awsClient) LookupSecret(ctx context.Context, id string) (*string, error) {
+	secret, err := getSecretValue(ctx, c.clients[config.AwsSourceConfig{}].secsClient, &secs.GetSecretValueInput{
+		SecretId: aws.String(id),
+	})
+	if err != nil || !secret.found {
+		return nil, err
+	}
+
+	value, err := secretString(config.AwsSecretsManagerValueConfig{}, secret)
+	if err != nil {
+		return nil, GettingValueError(id, err)
+	}
+
+	return value, nil
+}
+
+// awsParameterStoreReferenceName returns the reference name of a parameter config. Values got by
+// Path without Ref are set under top-level keys of the parameter names, which are known only
+// after getting parameters.
+func awsParameterStoreReferenceName(v config.AwsParameterStoreValueConfig) (string, bool) {
+	if len(v.Path) != 0 && (v.Ref == nil || len(*v.Ref) == 0) {
+		return "", false
+	}
+
+	return v.GetReferenceName(), true
+}
+
+// ssmParameterNames returns names which the parameter may be requested by.
+func ssmParameterNames(p ssmTypes.Parameter) []string {
+	selector := aws.ToString(p.Selector)
+	return []string{aws.ToString(p.Name) + selector, aws.ToString(p.ARN) + selector}
+}
+
+// getParameters returns parameters keyed by the requested names. Parameters not found are not
+// included. If GetParameters is denied, parameters are got one by one, so that a policy which
+// allows only GetParameter works.
+func getParameters(ctx context.Context, client ssmClient, names []string, decryption bool) (map[string]awsValue, error) {
+	output, err := client.GetParameters(ctx, &ssm.GetParametersInput{
+		Names:          names,
+		WithDecryption: aws.Bool(decryption),
+	})
+	if isAwsAccessDenied(err) {
+		return getParametersOneByOne(ctx, client, names, decryption)
+	}
+	if err != nil {
+		return nil, GettingValueError(strings.Join(names, ", "), err)
+	}
+
+	parameters := make(map[string]awsValue, len(names))
+	for _, p := range output.Parameters {
+		for _, name := range ssmParameterNames(p) {
+			parameters[name] = awsValue{value: p.Value, found: true}
+		}
+	}
+
+	return parameters, nil
+}
 
+func getParametersOneByOne(ctx context.Context, client ssmClient, names []string, decryption bool) (map[string]awsValue, error) {
+	parameters := make(map[string]awsValue, len(names))
+	for _, name := range names {
+		output, err := client.GetParameter(ctx, &ssm.GetParameterInput{
+			Name:           aws.String(name),
+			WithDecryption: aws.Bool(decryption),
+		})
 		if err != nil {
-			var notFoundErr *secsTypes.ResourceNotFoundException
-			if ignoreNotFound && errors.As(err, &notFoundErr) {
+			var notFoundErr *ssmTypes.ParameterNotFound
+			if errors.As(err, &notFoundErr) {
 				continue
 			}
-			return nil, gettingValueError(v.Name, err)
+			return nil, GettingValueError(name, err)
 		}
+		parameters[name] = awsValue{value: output.Parameter.Value, found: true}
+	}
 
-		if err := values.SetValue(v, output.SecretString); err != nil {
+	return parameters, nil
+}
+
+// getParametersByPath returns all parameters under the path.
+func getParametersByPath(ctx context.Context, client ssmClient, v config.AwsParameterStoreValueConfig) ([]ssmTypes.Parameter, error) {
+	paginator := ssm.NewGetParametersByPathPaginator(client, &ssm.GetParametersByPathInput{
+		Path:           &v.Path,
+		Recursive:      &v.Recursive,
+		WithDecryption: v.Decryption,
+	})
+
+	var parameters []ssmTypes.Parameter
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
 			return nil, err
 		}
+		parameters = append(parameters, output.Parameters...)
 	}
 
-	return values, nil
+	return parameters, nil
+}
+
+// setParametersByPath sets parameters under the path as a nested map keyed by the path
+// segments, e.g. "/app/prod/db/host" under "/app/prod" is set as {"db": {"host": value}}.
+func setParametersByPath(values values.Values, v config.AwsParameterStoreValueConfig, parameters []ssmTypes.Parameter, ignoreNotFound bool) error {
+	if len(parameters) == 0 {
+		if ignoreNotFound {
+			return nil
+		}
+		return GettingValueError(v.Path, &ssmTypes.ParameterNotFound{Message: aws.String("no parameters found under the path")})
+	}
+
+	prefix := strings.TrimSuffix(v.Path, "/") + "/"
+	for _, p := range parameters {
+		keys := strings.Split(strings.TrimPrefix(aws.ToString(p.Name), prefix), "/")
+		if v.Ref != nil && len(*v.Ref) != 0 {
+			keys = append([]string{*v.Ref}, keys...)
+		}
+
+		if err := values.SetValueByPath(v, keys, p.Value); err != nil {
+			return GettingValueError(aws.ToString(p.Name), err)
+		}
+	}
+
+	return nil
+}
+
+// secretString returns the secret string, or the binary secret encoded by BinaryEncoding.
+func secretString(v config.AwsSecretsManagerValueConfig, secret awsValue) (*string, error) {
+	if secret.value != nil || secret.binary == nil {
+		return secret.value, nil
+	}
+
+	var value string
+	switch v.BinaryEncoding {
+	case config.AwsSecretBinaryEncodingUnspecified, config.AwsSecretBinaryEncodingBase64:
+		value = base64.StdEncoding.EncodeToString(secret.binary)
+	case config.AwsSecretBinaryEncodingRaw:
+		value = string(secret.binary)
+	default:
+		return nil, fmt.Errorf("invalid binary encoding: %s", v.BinaryEncoding)
+	}
+
+	return &value, nil
+}
+
+// getSecretValue returns the secret. If the secret is not found, found of the returned value is
+// false.
+func getSecretValue(ctx context.Context, client secsClient, params *secs.GetSecretValueInput) (awsValue, error) {
+	output, err := client.GetSecretValue(ctx, params)
+	if err != nil {
+		var notFoundErr *secsTypes.ResourceNotFoundException
+		if errors.As(err, &notFoundErr) {
+			return awsValue{}, nil
+		}
+		return awsValue{}, GettingValueError(*params.SecretId, err)
+	}
+
+	return awsValue{value: output.SecretString, binary: output.SecretBinary, found: true}, nil
+}
+
+// batchGetSecretValue returns secrets keyed by the requested IDs. Secrets not found are not
+// included. If BatchGetSecretValue is denied, secrets are got one by one, so that a policy which
+// allows only GetSecretValue works.
+func batchGetSecretValue(ctx context.Context, client secsClient, ids []string) (map[string]awsValue, error) {
+	output, err := client.BatchGetSecretValue(ctx, &secs.BatchGetSecretValueInput{
+		SecretIdList: ids,
+	})
+	if isAwsAccessDenied(err) {
+		output, err = &secs.BatchGetSecretValueOutput{}, nil
+	}
+	if err != nil {
+		return nil, GettingValueError(strings.Join(ids, ", "), err)
+	}
+
+	secrets := make(map[string]awsValue, len(ids))
+	for _, s := range output.SecretValues {
+		for _, id := range ids {
+			if id == aws.ToString(s.Name) || id == aws.ToString(s.ARN) {
+				secrets[id] = awsValue{value: s.SecretString, binary: s.SecretBinary, found: true}
+			}
+		}
+	}
+
+	notFound := make(map[string]bool)
+	for _, e := range output.Errors {
+		if aws.ToString(e.ErrorCode) == secsErrorCodeNotFound {
+			notFound[aws.ToString(e.SecretId)] = true
+			continue
+		}
+		return nil, GettingValueError(aws.ToString(e.SecretId), fmt.Errorf("%s: %s", aws.ToString(e.ErrorCode), aws.ToString(e.Message)))
+	}
+
+	// A secret requested by a partial ARN can not be matched with the output, and no secret is in
+	// the output if the batch request is denied, so get them one by one.
+	for _, id := range ids {
+		if _, ok := secrets[id]; ok || notFound[id] {
+			continue
+		}
+
+		secret, err := getSecretValue(ctx, client, &secs.GetSecretValueInput{
+			SecretId: &id,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if secret.found {
+			secrets[id] = secret
+		}
+	}
+
+	return secrets, nil
+}
+
+func stringOrNil(s string) *string {
+	if len(s) == 0 {
+		return nil
+	}
+	return &s
 }
 
 type ssmClient interface {
 	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+	GetParameters(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersOutput, error)
+	GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error)
 }
 
 type ssmClientWithCache struct {
@@ -128,43 +725,154 @@ func (c ssmClientWithCache) GetParameter(ctx context.Context, params *ssm.GetPar
 		return c.getParameter(ctx, params, optFns...)
 	}
 
-	key := *params.Name // Name is required, so do not check nil
+	// Name is required, so do not check nil. Name includes the selector of the version or the
+	// label, e.g. "/name:3", so values of each version are cached separately.
+	key := *params.Name
 	isSensitive := params.WithDecryption != nil && *params.WithDecryption
 
-	// Load from cache.
-	value, expired, err := c.cache.Load(ctx, key, isSensitive)
+	value, err := GetWithCache(ctx, c.cache, key, isSensitive, isAwsTransientError, func(ctx context.Context) (*string, error) {
+		output, err := c.getParameter(ctx, params, optFns...)
+		if err != nil {
+			return nil, err
+		}
+		return output.Parameter.Value, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// If a cache value is expired or not found, get a value from the external store.
-	if value == nil || expired {
-		output, err := c.getParameter(ctx, params, optFns...)
+	return &ssm.GetParameterOutput{Parameter: &ssmTypes.Parameter{Value: value}}, nil
+}
+
+func (c ssmClientWithCache) getParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	output, err := c.client.GetParameter(ctx, params, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// GetParameters returns cached parameters and gets the rest from the external store in a single
+// request.
+func (c ssmClientWithCache) GetParameters(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersOutput, error) {
+	if c.cache == nil {
+		return c.client.GetParameters(ctx, params, optFns...)
+	}
+
+	isSensitive := params.WithDecryption != nil && *params.WithDecryption
+
+	// Load from cache. Names are used as cache keys in the same way as GetParameter.
+	output := &ssm.GetParametersOutput{}
+	var names []string
+	for _, name := range params.Names {
+		value, expired, err := c.cache.Load(ctx, name, isSensitive)
 		if err != nil {
 			return nil, err
 		}
-
-		// Create or update cache.
-		if err := c.cache.Save(ctx, key, output.Parameter.Value, isSensitive); err != nil {
-			return nil, err
+		if value == nil || expired {
+			names = append(names, name)
+			continue
 		}
+		output.Parameters = append(output.Parameters, ssmTypes.Parameter{Name: aws.String(name), Value: value})
+	}
+	if len(names) == 0 {
+		return output, nil
+	}
 
+	// Get values not cached or expired from the external store.
+	fetched, err := c.client.GetParameters(ctx, &ssm.GetParametersInput{
+		Names:          names,
+		WithDecryption: params.WithDecryption,
+	}, optFns...)
+	if err != nil {
+		// Expired values are used only if all of them are allowed by stale_if_error policy.
+		stale := make([]ssmTypes.Parameter, 0, len(names))
+		for _, name := range names {
+			value := loadStale(ctx, c.cache, name, isSensitive, isAwsTransientError, err)
+			if value == nil {
+				return nil, err
+			}
+			stale = append(stale, ssmTypes.Parameter{Name: aws.String(name), Value: value})
+		}
+		for _, name := range names {
+			warnStale(ctx, name, err)
+		}
+		output.Parameters = append(output.Parameters, stale...)
 		return output, nil
 	}
 
-	return &ssm.GetParameterOutput{Parameter: &ssmTypes.Parameter{Value: value}}, nil
+	// Create or update cache.
+	for _, p := range fetched.Parameters {
+		for _, name := range ssmParameterNames(p) {
+			if !slices.Contains(names, name) {
+				continue
+			}
+			if err := c.cache.Save(ctx, name, p.Value, isSensitive); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	output.Parameters = append(output.Parameters, fetched.Parameters...)
+	output.InvalidParameters = fetched.InvalidParameters
+
+	return output, nil
 }
 
-func (c ssmClientWithCache) getParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
-	output, err := c.client.GetParameter(ctx, params, optFns...)
+// GetParametersByPath returns all parameters under the path in a single page if the cache is
+// enabled, because the cache holds all parameters under the path.
+func (c ssmClientWithCache) GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	if c.cache == nil || params.NextToken != nil {
+		return c.client.GetParametersByPath(ctx, params, optFns...)
+	}
+
+	key := "path:" + *params.Path // Path is required, so do not check nil
+	if params.Recursive != nil && *params.Recursive {
+		key += "?recursive"
+	}
+	isSensitive := params.WithDecryption != nil && *params.WithDecryption
+
+	value, err := GetWithCache(ctx, c.cache, key, isSensitive, isAwsTransientError, func(ctx context.Context) (*string, error) {
+		parameters := make(map[string]string)
+		paginator := ssm.NewGetParametersByPathPaginator(c.client, params)
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx, optFns...)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range output.Parameters {
+				parameters[aws.ToString(p.Name)] = aws.ToString(p.Value)
+			}
+		}
+
+		b, err := json.Marshal(parameters)
+		if err != nil {
+			return nil, err
+		}
+		value := string(b)
+
+		return &value, nil
+	})
 	if err != nil {
 		return nil, err
 	}
+
+	parameters := make(map[string]string)
+	if err := json.Unmarshal([]byte(*value), &parameters); err != nil {
+		return nil, err
+	}
+
+	output := &ssm.GetParametersByPathOutput{Parameters: make([]ssmTypes.Parameter, 0, len(parameters))}
+	for _, name := range slices.Sorted(maps.Keys(parameters)) {
+		output.Parameters = append(output.Parameters, ssmTypes.Parameter{Name: aws.String(name), Value: aws.String(parameters[name])})
+	}
+
 	return output, nil
 }
 
 type secsClient interface {
 	GetSecretValue(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error)
+	BatchGetSecretValue(ctx context.Context, params *secs.BatchGetSecretValueInput, optFns ...func(*secs.Options)) (*secs.BatchGetSecretValueOutput, error)
 }
 
 type secsClientWithCache struct {
@@ -177,30 +885,34 @@ func (c secsClientWithCache) GetSecretValue(ctx context.Context, params *secs.Ge
 		return c.getSecretValue(ctx, params, optFns...)
 	}
 
-	key := *params.SecretId // SecretId is required, so do not check nil
+	key := secsCacheKey(params)
 
-	// Load from cache. Secret is always sensitive.
-	value, expired, err := c.cache.Load(ctx, key, true)
+	// Load from cache.
+	output, expired, err := c.loadSecret(ctx, key)
 	if err != nil {
 		return nil, err
 	}
 
 	// If a cache value is expired or not found, get a value from the external store.
-	if value == nil || expired {
+	if output == nil || expired {
 		output, err := c.getSecretValue(ctx, params, optFns...)
 		if err != nil {
+			if stale := c.loadStaleSecret(ctx, key, err); stale != nil {
+				warnStale(ctx, key, err)
+				return stale, nil
+			}
 			return nil, err
 		}
 
 		// Create or update cache.
-		if err := c.cache.Save(ctx, key, output.SecretString, true); err != nil {
+		if err := c.saveSecret(ctx, key, output.SecretString, output.SecretBinary); err != nil {
 			return nil, err
 		}
 
 		return output, nil
 	}
 
-	return &secs.GetSecretValueOutput{SecretString: value}, nil
+	return output, nil
 }
 
 func (c secsClientWithCache) getSecretValue(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error) {
@@ -211,9 +923,232 @@ func (c secsClientWithCache) getSecretValue(ctx context.Context, params *secs.Ge
 	return output, nil
 }
 
-func getAwsAccountId(sdkConfig *aws.Config) (string, error) {
-	stsClient := sts.NewFromConfig(*sdkConfig)
-	output, err := stsClient.GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
+// loadSecret loads the secret string or the binary secret from the cache. If neither is found,
+// returns nil.
+func (c secsClientWithCache) loadSecret(ctx context.Context, key string) (*secs.GetSecretValueOutput, bool, error) {
+	// Secret is always sensitive.
+	value, expired, err := c.cache.Load(ctx, key, true)
+	if err != nil {
+		return nil, false, err
+	}
+	if value != nil {
+		return &secs.GetSecretValueOutput{SecretString: value}, expired, nil
+	}
+
+	value, expired, err = c.cache.Load(ctx, key+secsBinaryCacheKeySuffix, true)
+	if err != nil {
+		return nil, false, err
+	}
+	if value == nil {
+		return nil, false, nil
+	}
+	binary, err := base64.StdEncoding.DecodeString(*value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &secs.GetSecretValueOutput{SecretBinary: binary}, expired, nil
+}
+
+// loadStaleSecret loads the expired secret string or binary secret from the cache to be used
+// instead of err. If neither is allowed by stale_if_error policy, returns nil.
+func (c secsClientWithCache) loadStaleSecret(ctx context.Context, key string, err error) *secs.GetSecretValueOutput {
+	if value := loadStale(ctx, c.cache, key, true, isAwsTransientError, err); value != nil {
+		return &secs.GetSecretValueOutput{SecretString: value}
+	}
+
+	value := loadStale(ctx, c.cache, key+secsBinaryCacheKeySuffix, true, isAwsTransientError, err)
+	if value == nil {
+		return nil
+	}
+	binary, decodeErr := base64.StdEncoding.DecodeString(*value)
+	if decodeErr != nil {
+		return nil
+	}
+
+	return &secs.GetSecretValueOutput{SecretBinary: binary}
+}
+
+// saveSecret saves the secret string or the binary secret to the cache.
+func (c secsClientWithCache) saveSecret(ctx context.Context, key string, value *string, binary []byte) error {
+	if value == nil && binary != nil {
+		encoded := base64.StdEncoding.EncodeToString(binary)
+		return c.cache.Save(ctx, key+secsBinaryCacheKeySuffix, &encoded, true)
+	}
+
+	return c.cache.Save(ctx, key, value, true)
+}
+
+// secsCacheKey returns a cache key of the secret. The version is appended as a query, e.g.
+// "name?version_stage=AWSPREVIOUS". "?" is not allowed in secret names and ARNs.
+func secsCacheKey(params *secs.GetSecretValueInput) string {
+	key := *params.SecretId // SecretId is required, so do not check nil
+
+	query := url.Values{}
+	if params.VersionId != nil {
+		query.Set("version_id", *params.VersionId)
+	}
+	if params.VersionStage != nil {
+		query.Set("version_stage", *params.VersionStage)
+	}
+	if len(query) != 0 {
+		key += "?" + query.Encode()
+	}
+
+	return key
+}
+
+// BatchGetSecretValue returns cached secrets and gets the rest from the external store in a
+// single request.
+func (c secsClientWithCache) BatchGetSecretValue(ctx context.Context, params *secs.BatchGetSecretValueInput, optFns ...func(*secs.Options)) (*secs.BatchGetSecretValueOutput, error) {
+	if c.cache == nil {
+		return c.client.BatchGetSecretValue(ctx, params, optFns...)
+	}
+
+	// Load from cache. IDs are used as cache keys in the same way as GetSecretValue.
+	output := &secs.BatchGetSecretValueOutput{}
+	var ids []string
+	for _, id := range params.SecretIdList {
+		secret, expired, err := c.loadSecret(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if secret == nil || expired {
+			ids = append(ids, id)
+			continue
+		}
+		output.SecretValues = append(output.SecretValues, secsTypes.SecretValueEntry{
+			Name:         aws.String(id),
+			SecretString: secret.SecretString,
+			SecretBinary: secret.SecretBinary,
+		})
+	}
+	if len(ids) == 0 {
+		return output, nil
+	}
+
+	// Get values not cached or expired from the external store.
+	fetched, err := c.client.BatchGetSecretValue(ctx, &secs.BatchGetSecretValueInput{
+		SecretIdList: ids,
+	}, optFns...)
+	if err != nil {
+		// Expired secrets are used only if all of them are allowed by stale_if_error policy.
+		stale := make([]secsTypes.SecretValueEntry, 0, len(ids))
+		for _, id := range ids {
+			secret := c.loadStaleSecret(ctx, id, err)
+			if secret == nil {
+				return nil, err
+			}
+			stale = append(stale, secsTypes.SecretValueEntry{
+				Name:         aws.String(id),
+				SecretString: secret.SecretString,
+				SecretBinary: secret.SecretBinary,
+			})
+		}
+		for _, id := range ids {
+			warnStale(ctx, id, err)
+		}
+		output.SecretValues = append(output.SecretValues, stale...)
+		return output, nil
+	}
+
+	// Create or update cache.
+	for _, s := range fetched.SecretValues {
+		for _, id := range ids {
+			if id != aws.ToString(s.Name) && id != aws.ToString(s.ARN) {
+				continue
+			}
+			if err := c.saveSecret(ctx, id, s.SecretString, s.SecretBinary); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	output.SecretValues = append(output.SecretValues, fetched.SecretValues...)
+	output.Errors = fetched.Errors
+
+	return output, nil
+}
+
+// isAwsTransientError reports whether err is caused by throttling, network errors or server
+// errors, with which expired cached values can be used by stale_if_error policy.
+func isAwsTransientError(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		if _, ok := retry.DefaultThrottleErrorCodes[apiErr.ErrorCode()]; ok {
+			return true
+		}
+	}
+
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		status := respErr.HTTPStatusCode()
+		return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+	}
+
+	var sendErr *smithyhttp.RequestSendError
+	var netErr net.Error
+	return errors.As(err, &sendErr) || errors.As(err, &netErr)
+}
+
+// isAwsAccessDenied reports whether err is caused by a denied action.
+func isAwsAccessDenied(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	code := apiErr.ErrorCode()
+	return code == "AccessDeniedException" || code == "AccessDenied"
+}
+
+func newStsClient(sdkConfig aws.Config, endpoints config.AwsEndpointsConfig) *sts.Client {
+	return sts.NewFromConfig(sdkConfig, func(o *sts.Options) {
+		if len(endpoints.Sts) != 0 {
+			o.BaseEndpoint = aws.String(endpoints.Sts)
+		}
+	})
+}
+
+// lookupAwsAccountId returns the AWS account ID of the credentials. If the credentials do not
+// have it, e.g. unless AWS_ACCOUNT_ID or SSO is used, it is looked up by STS and saved in accounts
+// by the access key ID. The saved one is used if STS fails, so that expired cached values can
+// still be used by stale_if_error policy.
+func lookupAwsAccountId(ctx context.Context, sdkConfig aws.Config, endpoints config.AwsEndpointsConfig, accounts cache.Cache, timeout time.Duration) (string, error) {
+	lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var creds aws.Credentials
+	if sdkConfig.Credentials != nil {
+		// If retrieving fails, STS fails with the same error.
+		creds, _ = sdkConfig.Credentials.Retrieve(lookupCtx)
+	}
+	if len(creds.AccountID) != 0 {
+		return creds.AccountID, nil
+	}
+
+	accountID, err := getAwsAccountId(lookupCtx, newStsClient(sdkConfig, endpoints))
+	if len(creds.AccessKeyID) == 0 {
+		return accountID, err
+	}
+	if err != nil {
+		saved, _, loadErr := accounts.Load(ctx, creds.AccessKeyID, false)
+		if loadErr != nil || saved == nil {
+			return "", err
+		}
+		slog.WarnContext(ctx, "using the aws account id looked up before because looking up failed", "account", *saved, "error", err)
+		return *saved, nil
+	}
+
+	if err := accounts.Save(ctx, creds.AccessKeyID, &accountID, false); err != nil {
+		return "", err
+	}
+
+	return accountID, nil
+}
+
+func getAwsAccountId(ctx context.Context, stsClient *sts.Client) (string, error) {
+	output, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 	if err != nil {
 		return "", err
 	}
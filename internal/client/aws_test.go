@@ -18,14 +18,33 @@ package client
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	secs "github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	secsTypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	ssmTypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/dwango/yashiro/internal/client/cache"
 	"github.com/dwango/yashiro/internal/values"
 	"github.com/dwango/yashiro/pkg/config"
@@ -41,6 +60,13 @@ func Test_newAwsClient(t *testing.T) {
 		want    Client
 		wantErr bool
 	}{
+		{
+			name: "ok: aws is not configured",
+			args: args{
+				cfg: &config.Config{},
+			},
+			want: nil,
+		},
 		{
 			name: "error: aws sdk config is nil",
 			args: args{
@@ -48,6 +74,30 @@ func Test_newAwsClient(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "error: invalid binary encoding",
+			args: args{
+				cfg: &config.Config{Aws: &config.AwsConfig{
+					SecretsManagerValues: []config.AwsSecretsManagerValueConfig{
+						{ValueConfig: config.ValueConfig{Name: "name"}, BinaryEncoding: "hex"},
+					},
+					SdkConfig: &aws.Config{},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: both version and label are set",
+			args: args{
+				cfg: &config.Config{Aws: &config.AwsConfig{
+					ParameterStoreValues: []config.AwsParameterStoreValueConfig{
+						{ValueConfig: config.ValueConfig{Name: "/name"}, Version: 3, Label: "prod"},
+					},
+					SdkConfig: &aws.Config{},
+				}},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -69,12 +119,92 @@ func (m mockSsmClient) GetParameter(ctx context.Context, params *ssm.GetParamete
 	return m(ctx, params, optFns...)
 }
 
+// GetParameters gets each parameter by the mock of GetParameter.
+func (m mockSsmClient) GetParameters(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersOutput, error) {
+	output := &ssm.GetParametersOutput{}
+	for _, name := range params.Names {
+		o, err := m(ctx, &ssm.GetParameterInput{Name: aws.String(name), WithDecryption: params.WithDecryption}, optFns...)
+		if err != nil {
+			var notFoundErr *ssmTypes.ParameterNotFound
+			if errors.As(err, &notFoundErr) {
+				output.InvalidParameters = append(output.InvalidParameters, name)
+				continue
+			}
+			return nil, err
+		}
+		output.Parameters = append(output.Parameters, ssmTypes.Parameter{Name: aws.String(name), Value: o.Parameter.Value})
+	}
+	return output, nil
+}
+
+func (m mockSsmClient) GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	return nil, &ssmTypes.InternalServerError{}
+}
+
+// mockSecsBatchClient is a mock of BatchGetSecretValue. GetSecretValue returns "test".
+type mockSecsBatchClient func(ctx context.Context, params *secs.BatchGetSecretValueInput, optFns ...func(*secs.Options)) (*secs.BatchGetSecretValueOutput, error)
+
+func (m mockSecsBatchClient) GetSecretValue(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error) {
+	return &secs.GetSecretValueOutput{SecretString: stringPtr("test")}, nil
+}
+
+func (m mockSecsBatchClient) BatchGetSecretValue(ctx context.Context, params *secs.BatchGetSecretValueInput, optFns ...func(*secs.Options)) (*secs.BatchGetSecretValueOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+// mockSsmBatchClient is a mock of GetParameters.
+type mockSsmBatchClient func(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersOutput, error)
+
+func (m mockSsmBatchClient) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	return nil, &ssmTypes.InternalServerError{}
+}
+
+func (m mockSsmBatchClient) GetParameters(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+func (m mockSsmBatchClient) GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	return nil, &ssmTypes.InternalServerError{}
+}
+
+type mockSsmPathClient func(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error)
+
+func (m mockSsmPathClient) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	return nil, &ssmTypes.InternalServerError{}
+}
+
+func (m mockSsmPathClient) GetParameters(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersOutput, error) {
+	return nil, &ssmTypes.InternalServerError{}
+}
+
+func (m mockSsmPathClient) GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
 type mockSecsClient func(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error)
 
 func (m mockSecsClient) GetSecretValue(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error) {
 	return m(ctx, params, optFns...)
 }
 
+// BatchGetSecretValue gets each secret by the mock of GetSecretValue.
+func (m mockSecsClient) BatchGetSecretValue(ctx context.Context, params *secs.BatchGetSecretValueInput, optFns ...func(*secs.Options)) (*secs.BatchGetSecretValueOutput, error) {
+	output := &secs.BatchGetSecretValueOutput{}
+	for _, id := range params.SecretIdList {
+		o, err := m(ctx, &secs.GetSecretValueInput{SecretId: aws.String(id)}, optFns...)
+		if err != nil {
+			var notFoundErr *secsTypes.ResourceNotFoundException
+			if errors.As(err, &notFoundErr) {
+				output.Errors = append(output.Errors, secsTypes.APIErrorType{SecretId: aws.String(id), ErrorCode: aws.String(secsErrorCodeNotFound)})
+				continue
+			}
+			return nil, err
+		}
+		output.SecretValues = append(output.SecretValues, secsTypes.SecretValueEntry{Name: aws.String(id), SecretString: o.SecretString, SecretBinary: o.SecretBinary})
+	}
+	return output, nil
+}
+
 var (
 	textStrSsmClient = mockSsmClient(func(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
 		return &ssm.GetParameterOutput{
@@ -84,6 +214,32 @@ var (
 		}, nil
 	})
 
+	// pagedSsmPathClient returns the parameters under "/app/prod" in two pages.
+	pagedSsmPathClient = mockSsmPathClient(func(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+		if params.NextToken == nil {
+			return &ssm.GetParametersByPathOutput{
+				Parameters: []ssmTypes.Parameter{
+					{Name: stringPtr("/app/prod/db/host"), Value: stringPtr("localhost")},
+				},
+				NextToken: stringPtr("next"),
+			}, nil
+		}
+		return &ssm.GetParametersByPathOutput{
+			Parameters: []ssmTypes.Parameter{
+				{Name: stringPtr("/app/prod/db/port"), Value: stringPtr("5432")},
+				{Name: stringPtr("/app/prod/name"), Value: stringPtr("app")},
+			},
+		}, nil
+	})
+
+	throttlingSsmClient = mockSsmClient(func(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+		return nil, &smithy.GenericAPIError{Code: "ThrottlingException"}
+	})
+
+	throttlingSecsClient = mockSecsClient(func(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error) {
+		return nil, &smithy.GenericAPIError{Code: "ThrottlingException"}
+	})
+
 	textStrSecsClient = mockSecsClient(func(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error) {
 		return &secs.GetSecretValueOutput{
 			SecretString: stringPtr("test"),
@@ -96,7 +252,7 @@ func Test_awsClient_GetValues(t *testing.T) {
 		ssmClient           ssmClient
 		secsClient          secsClient
 		parameterStoreValue []config.AwsParameterStoreValueConfig
-		secretsManagerValue []config.ValueConfig
+		secretsManagerValue []config.AwsSecretsManagerValueConfig
 	}
 	type args struct {
 		ctx            context.Context
@@ -125,8 +281,8 @@ func Test_awsClient_GetValues(t *testing.T) {
 				parameterStoreValue: []config.AwsParameterStoreValueConfig{
 					{ValueConfig: config.ValueConfig{Name: "ssmKey"}, Decryption: nil},
 				},
-				secretsManagerValue: []config.ValueConfig{
-					{Name: "secsKey"},
+				secretsManagerValue: []config.AwsSecretsManagerValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "secsKey"}},
 				},
 			},
 			args: args{
@@ -153,8 +309,8 @@ func Test_awsClient_GetValues(t *testing.T) {
 				parameterStoreValue: []config.AwsParameterStoreValueConfig{
 					{ValueConfig: config.ValueConfig{Name: "ssmKey", IsJSON: true}},
 				},
-				secretsManagerValue: []config.ValueConfig{
-					{Name: "secsKey", IsJSON: true},
+				secretsManagerValue: []config.AwsSecretsManagerValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "secsKey", IsJSON: true}},
 				},
 			},
 			args: args{
@@ -171,8 +327,140 @@ func Test_awsClient_GetValues(t *testing.T) {
 				parameterStoreValue: []config.AwsParameterStoreValueConfig{
 					{ValueConfig: config.ValueConfig{Name: "ssmKey"}},
 				},
-				secretsManagerValue: []config.ValueConfig{
-					{Name: "secsKey"},
+				secretsManagerValue: []config.AwsSecretsManagerValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "secsKey"}},
+				},
+			},
+			args: args{
+				ctx:            context.Background(),
+				ignoreNotFound: true,
+			},
+			want: values.Values{},
+		},
+		{
+			name: "ok: version and label",
+			fields: fields{
+				ssmClient: mockSsmClient(func(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+					return &ssm.GetParameterOutput{
+						Parameter: &ssmTypes.Parameter{
+							Value: stringPtr("value of " + *params.Name),
+						},
+					}, nil
+				}),
+				secsClient: textStrSecsClient,
+				parameterStoreValue: []config.AwsParameterStoreValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "/name", Ref: stringPtr("latest")}},
+					{ValueConfig: config.ValueConfig{Name: "/name", Ref: stringPtr("version")}, Version: 3},
+					{ValueConfig: config.ValueConfig{Name: "/name", Ref: stringPtr("label")}, Label: "prod"},
+				},
+			},
+			args: args{
+				ctx:            context.Background(),
+				ignoreNotFound: false,
+			},
+			want: values.Values{"latest": "value of /name", "version": "value of /name:3", "label": "value of /name:prod"},
+		},
+		{
+			name: "ok: secret version",
+			fields: fields{
+				ssmClient: textStrSsmClient,
+				secsClient: mockSecsClient(func(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error) {
+					value := "current"
+					if params.VersionStage != nil {
+						value = *params.VersionStage
+					}
+					if params.VersionId != nil {
+						value = *params.VersionId
+					}
+					return &secs.GetSecretValueOutput{SecretString: &value}, nil
+				}),
+				secretsManagerValue: []config.AwsSecretsManagerValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "secsKey", Ref: stringPtr("current")}},
+					{ValueConfig: config.ValueConfig{Name: "secsKey", Ref: stringPtr("previous")}, VersionStage: "AWSPREVIOUS"},
+					{ValueConfig: config.ValueConfig{Name: "secsKey", Ref: stringPtr("id")}, VersionID: "EXAMPLE1-90ab-cdef-fedc-ba987EXAMPLE"},
+				},
+			},
+			args: args{
+				ctx:            context.Background(),
+				ignoreNotFound: false,
+			},
+			want: values.Values{"current": "current", "previous": "AWSPREVIOUS", "id": "EXAMPLE1-90ab-cdef-fedc-ba987EXAMPLE"},
+		},
+		{
+			name: "ok: binary secret",
+			fields: fields{
+				ssmClient: textStrSsmClient,
+				secsClient: mockSecsClient(func(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error) {
+					return &secs.GetSecretValueOutput{SecretBinary: []byte("binary")}, nil
+				}),
+				secretsManagerValue: []config.AwsSecretsManagerValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "secsKey", Ref: stringPtr("default")}},
+					{ValueConfig: config.ValueConfig{Name: "secsKey", Ref: stringPtr("base64")}, BinaryEncoding: config.AwsSecretBinaryEncodingBase64},
+					{ValueConfig: config.ValueConfig{Name: "secsKey", Ref: stringPtr("raw")}, BinaryEncoding: config.AwsSecretBinaryEncodingRaw},
+				},
+			},
+			args: args{
+				ctx:            context.Background(),
+				ignoreNotFound: false,
+			},
+			want: values.Values{"default": "YmluYXJ5", "base64": "YmluYXJ5", "raw": "binary"},
+		},
+		{
+			name: "error: invalid binary encoding",
+			fields: fields{
+				ssmClient: textStrSsmClient,
+				secsClient: mockSecsClient(func(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error) {
+					return &secs.GetSecretValueOutput{SecretBinary: []byte("binary")}, nil
+				}),
+				secretsManagerValue: []config.AwsSecretsManagerValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "secsKey"}, BinaryEncoding: "hex"},
+				},
+			},
+			args: args{
+				ctx:            context.Background(),
+				ignoreNotFound: false,
+			},
+			wantErr: true,
+		},
+		{
+			name: "ok: path with ref",
+			fields: fields{
+				ssmClient:  pagedSsmPathClient,
+				secsClient: textStrSecsClient,
+				parameterStoreValue: []config.AwsParameterStoreValueConfig{
+					{ValueConfig: config.ValueConfig{Ref: stringPtr("app")}, Path: "/app/prod/", Recursive: true},
+				},
+			},
+			args: args{
+				ctx:            context.Background(),
+				ignoreNotFound: false,
+			},
+			want: values.Values{"app": map[string]any{"db": map[string]any{"host": "localhost", "port": "5432"}, "name": "app"}},
+		},
+		{
+			name: "ok: path without ref",
+			fields: fields{
+				ssmClient:  pagedSsmPathClient,
+				secsClient: textStrSecsClient,
+				parameterStoreValue: []config.AwsParameterStoreValueConfig{
+					{Path: "/app/prod", Recursive: true},
+				},
+			},
+			args: args{
+				ctx:            context.Background(),
+				ignoreNotFound: false,
+			},
+			want: values.Values{"db": map[string]any{"host": "localhost", "port": "5432"}, "name": "app"},
+		},
+		{
+			name: "ok: ignore no parameters under path",
+			fields: fields{
+				ssmClient: mockSsmPathClient(func(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+					return &ssm.GetParametersByPathOutput{}, nil
+				}),
+				secsClient: textStrSecsClient,
+				parameterStoreValue: []config.AwsParameterStoreValueConfig{
+					{ValueConfig: config.ValueConfig{Ref: stringPtr("app")}, Path: "/app/prod"},
 				},
 			},
 			args: args{
@@ -181,6 +469,45 @@ func Test_awsClient_GetValues(t *testing.T) {
 			},
 			want: values.Values{},
 		},
+		{
+			name: "error: no parameters under path",
+			fields: fields{
+				ssmClient: mockSsmPathClient(func(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+					return &ssm.GetParametersByPathOutput{}, nil
+				}),
+				secsClient: textStrSecsClient,
+				parameterStoreValue: []config.AwsParameterStoreValueConfig{
+					{ValueConfig: config.ValueConfig{Ref: stringPtr("app")}, Path: "/app/prod"},
+				},
+			},
+			args: args{
+				ctx:            context.Background(),
+				ignoreNotFound: false,
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: conflict path",
+			fields: fields{
+				ssmClient: mockSsmPathClient(func(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+					return &ssm.GetParametersByPathOutput{
+						Parameters: []ssmTypes.Parameter{
+							{Name: stringPtr("/app/prod/db"), Value: stringPtr("db")},
+							{Name: stringPtr("/app/prod/db/host"), Value: stringPtr("localhost")},
+						},
+					}, nil
+				}),
+				secsClient: textStrSecsClient,
+				parameterStoreValue: []config.AwsParameterStoreValueConfig{
+					{Path: "/app/prod", Recursive: true},
+				},
+			},
+			args: args{
+				ctx:            context.Background(),
+				ignoreNotFound: true,
+			},
+			wantErr: true,
+		},
 		{
 			name: "error: return not found from ssm",
 			fields: fields{
@@ -189,7 +516,7 @@ func Test_awsClient_GetValues(t *testing.T) {
 				parameterStoreValue: []config.AwsParameterStoreValueConfig{
 					{ValueConfig: config.ValueConfig{Name: "ssmKey"}},
 				},
-				secretsManagerValue: []config.ValueConfig{},
+				secretsManagerValue: []config.AwsSecretsManagerValueConfig{},
 			},
 			args: args{
 				ctx:            context.Background(),
@@ -203,8 +530,8 @@ func Test_awsClient_GetValues(t *testing.T) {
 				ssmClient:           textStrSsmClient,
 				secsClient:          notFoundErrSecsClient,
 				parameterStoreValue: []config.AwsParameterStoreValueConfig{},
-				secretsManagerValue: []config.ValueConfig{
-					{Name: "secsKey"},
+				secretsManagerValue: []config.AwsSecretsManagerValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "secsKey"}},
 				},
 			},
 			args: args{
@@ -223,7 +550,7 @@ func Test_awsClient_GetValues(t *testing.T) {
 				parameterStoreValue: []config.AwsParameterStoreValueConfig{
 					{ValueConfig: config.ValueConfig{Name: "ssmKey"}},
 				},
-				secretsManagerValue: []config.ValueConfig{},
+				secretsManagerValue: []config.AwsSecretsManagerValueConfig{},
 			},
 			args: args{
 				ctx:            context.Background(),
@@ -239,8 +566,8 @@ func Test_awsClient_GetValues(t *testing.T) {
 					return nil, &secsTypes.InternalServiceError{}
 				}),
 				parameterStoreValue: []config.AwsParameterStoreValueConfig{},
-				secretsManagerValue: []config.ValueConfig{
-					{Name: "secsKey"},
+				secretsManagerValue: []config.AwsSecretsManagerValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "secsKey"}},
 				},
 			},
 			args: args{
@@ -253,8 +580,9 @@ func Test_awsClient_GetValues(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := awsClient{
-				ssmClient:           tt.fields.ssmClient,
-				secsClient:          tt.fields.secsClient,
+				clients: map[config.AwsSourceConfig]awsServiceClients{
+					{}: {ssmClient: tt.fields.ssmClient, secsClient: tt.fields.secsClient},
+				},
 				parameterStoreValue: tt.fields.parameterStoreValue,
 				secretsManagerValue: tt.fields.secretsManagerValue,
 			}
@@ -270,88 +598,1038 @@ func Test_awsClient_GetValues(t *testing.T) {
 	}
 }
 
-func Test_ssmClientWithCache_GetParameter(t *testing.T) {
-	var params = &ssm.GetParameterInput{Name: stringPtr("any")}
-	var textStrSsmClientWant = &ssm.GetParameterOutput{Parameter: &ssmTypes.Parameter{Value: stringPtr("test")}}
+// mockBatchAwsClient records batch requests to Parameter Store and Secrets Manager. Every
+// parameter and secret exists and its value is the same as the name.
+type mockBatchAwsClient struct {
+	mu          sync.Mutex
+	batchSizes  []int
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
 
-	type fields struct {
-		client ssmClient
-		cache  cache.Cache
+func (m *mockBatchAwsClient) record(size int) {
+	n := m.inFlight.Add(1)
+	for {
+		max := m.maxInFlight.Load()
+		if n <= max || m.maxInFlight.CompareAndSwap(max, n) {
+			break
+		}
 	}
-	type args struct {
-		ctx    context.Context
-		params *ssm.GetParameterInput
-		optFns []func(*ssm.Options)
+
+	m.mu.Lock()
+	m.batchSizes = append(m.batchSizes, size)
+	m.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+	m.inFlight.Add(-1)
+}
+
+func (m *mockBatchAwsClient) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	return nil, &ssmTypes.InternalServerError{}
+}
+
+func (m *mockBatchAwsClient) GetParameters(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersOutput, error) {
+	m.record(len(params.Names))
+	output := &ssm.GetParametersOutput{}
+	for _, name := range params.Names {
+		output.Parameters = append(output.Parameters, ssmTypes.Parameter{Name: aws.String(name), Value: aws.String(name)})
+	}
+	return output, nil
+}
+
+func (m *mockBatchAwsClient) GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	return nil, &ssmTypes.InternalServerError{}
+}
+
+func (m *mockBatchAwsClient) GetSecretValue(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error) {
+	return nil, &secsTypes.InternalServiceError{}
+}
+
+func (m *mockBatchAwsClient) BatchGetSecretValue(ctx context.Context, params *secs.BatchGetSecretValueInput, optFns ...func(*secs.Options)) (*secs.BatchGetSecretValueOutput, error) {
+	m.record(len(params.SecretIdList))
+	output := &secs.BatchGetSecretValueOutput{}
+	for _, id := range params.SecretIdList {
+		output.SecretValues = append(output.SecretValues, secsTypes.SecretValueEntry{Name: aws.String(id), SecretString: aws.String(id)})
+	}
+	return output, nil
+}
+
+func Test_awsClient_GetValues_batch(t *testing.T) {
+	var parameterStoreValue []config.AwsParameterStoreValueConfig
+	var secretsManagerValue []config.AwsSecretsManagerValueConfig
+	want := values.Values{}
+	for i := range 25 {
+		name := fmt.Sprintf("/ssm/%d", i)
+		parameterStoreValue = append(parameterStoreValue, config.AwsParameterStoreValueConfig{
+			ValueConfig: config.ValueConfig{Name: name},
+			Decryption:  aws.Bool(i%5 == 0),
+		})
+		want[name] = name
+	}
+	for i := range 45 {
+		name := fmt.Sprintf("secs/%d", i)
+		secretsManagerValue = append(secretsManagerValue, config.AwsSecretsManagerValueConfig{ValueConfig: config.ValueConfig{Name: name}})
+		want[name] = name
+	}
+	// duplicated names are requested once
+	parameterStoreValue = append(parameterStoreValue, config.AwsParameterStoreValueConfig{
+		ValueConfig: config.ValueConfig{Name: "/ssm/1", Ref: stringPtr("dup")},
+	})
+	want["dup"] = "/ssm/1"
+
+	m := &mockBatchAwsClient{}
+	c := awsClient{
+		clients: map[config.AwsSourceConfig]awsServiceClients{
+			{}: {ssmClient: m, secsClient: m},
+		},
+		parameterStoreValue: parameterStoreValue,
+		secretsManagerValue: secretsManagerValue,
+		maxConcurrency:      2,
+	}
+	got, err := c.GetValues(context.Background(), false)
+	if err != nil {
+		t.Fatalf("awsClient.GetValues() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("awsClient.GetValues() = %v, want %v", got, want)
+	}
+
+	// 20 parameters without decryption: 10+10, 5 parameters with decryption: 5,
+	// 45 secrets: 20+20+5
+	slices.Sort(m.batchSizes)
+	if wantSizes := []int{5, 5, 10, 10, 20, 20}; !reflect.DeepEqual(m.batchSizes, wantSizes) {
+		t.Errorf("batch sizes = %v, want %v", m.batchSizes, wantSizes)
+	}
+	if max := m.maxInFlight.Load(); max > 2 {
+		t.Errorf("max concurrent requests = %d, want <= 2", max)
+	}
+}
+
+// mockDeniedBatchAwsClient denies batch requests as a policy which allows only GetParameter
+// and GetSecretValue. Every parameter and secret exists except "notfound", and its value is the
+// same as the name.
+type mockDeniedBatchAwsClient struct{}
+
+func (m mockDeniedBatchAwsClient) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	if strings.Contains(*params.Name, "notfound") {
+		return nil, &ssmTypes.ParameterNotFound{}
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssmTypes.Parameter{Value: params.Name}}, nil
+}
+
+func (m mockDeniedBatchAwsClient) GetParameters(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "AccessDeniedException"}
+}
+
+func (m mockDeniedBatchAwsClient) GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	return nil, &ssmTypes.InternalServerError{}
+}
+
+func (m mockDeniedBatchAwsClient) GetSecretValue(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error) {
+	if strings.Contains(*params.SecretId, "notfound") {
+		return nil, &secsTypes.ResourceNotFoundException{}
 	}
+	return &secs.GetSecretValueOutput{SecretString: params.SecretId}, nil
+}
+
+func (m mockDeniedBatchAwsClient) BatchGetSecretValue(ctx context.Context, params *secs.BatchGetSecretValueInput, optFns ...func(*secs.Options)) (*secs.BatchGetSecretValueOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "AccessDeniedException"}
+}
+
+func Test_awsClient_GetValues_batchDenied(t *testing.T) {
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		want    *ssm.GetParameterOutput
-		wantErr bool
+		name           string
+		parameterNames []string
+		secretNames    []string
+		ignoreNotFound bool
+		want           values.Values
+		wantErr        bool
 	}{
 		{
-			name: "ok: get from cache",
-			fields: fields{
-				client: nil,
-				cache:  mockCache{load: mockLoadFunc, save: mockSaveFunc},
-			},
-			args: args{
-				ctx:    context.Background(),
-				params: params,
-			},
-			want: &ssm.GetParameterOutput{Parameter: &ssmTypes.Parameter{Value: stringPtr("value")}},
+			name:           "get one by one",
+			parameterNames: []string{"/ssm/1", "/ssm/2"},
+			secretNames:    []string{"secs/1", "secs/2"},
+			want:           values.Values{"/ssm/1": "/ssm/1", "/ssm/2": "/ssm/2", "secs/1": "secs/1", "secs/2": "secs/2"},
 		},
 		{
-			name: "ok: get from cache(cache disabled)",
-			fields: fields{
-				client: textStrSsmClient,
-				cache:  nil,
-			},
-			args: args{
-				ctx:    context.Background(),
-				params: &ssm.GetParameterInput{Name: stringPtr("key")},
-			},
-			want: textStrSsmClientWant,
+			name:           "not found is ignored",
+			parameterNames: []string{"/ssm/1", "/ssm/notfound"},
+			secretNames:    []string{"secs/1", "secs/notfound"},
+			ignoreNotFound: true,
+			want:           values.Values{"/ssm/1": "/ssm/1", "secs/1": "secs/1"},
 		},
 		{
-			name: "ok: get from client(no cache)",
-			fields: fields{
+			name:           "parameter not found",
+			parameterNames: []string{"/ssm/notfound"},
+			wantErr:        true,
+		},
+		{
+			name:        "secret not found",
+			secretNames: []string{"secs/notfound"},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parameterStoreValue []config.AwsParameterStoreValueConfig
+			for _, name := range tt.parameterNames {
+				parameterStoreValue = append(parameterStoreValue, config.AwsParameterStoreValueConfig{ValueConfig: config.ValueConfig{Name: name}})
+			}
+			var secretsManagerValue []config.AwsSecretsManagerValueConfig
+			for _, name := range tt.secretNames {
+				secretsManagerValue = append(secretsManagerValue, config.AwsSecretsManagerValueConfig{ValueConfig: config.ValueConfig{Name: name}})
+			}
+
+			c := awsClient{
+				clients: map[config.AwsSourceConfig]awsServiceClients{
+					{}: {ssmClient: mockDeniedBatchAwsClient{}, secsClient: mockDeniedBatchAwsClient{}},
+				},
+				parameterStoreValue: parameterStoreValue,
+				secretsManagerValue: secretsManagerValue,
+			}
+			got, err := c.GetValues(context.Background(), tt.ignoreNotFound)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("awsClient.GetValues() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("awsClient.GetValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_awsClient_GetValues_sources(t *testing.T) {
+	otherSource := config.AwsSourceConfig{Region: "us-east-1", RoleArn: "arn:aws:iam::012345678901:role/other"}
+	otherSsmClient := mockSsmClient(func(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+		return &ssm.GetParameterOutput{Parameter: &ssmTypes.Parameter{Value: stringPtr("other")}}, nil
+	})
+	otherSecsClient := mockSecsClient(func(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error) {
+		return &secs.GetSecretValueOutput{SecretString: stringPtr("other")}, nil
+	})
+
+	c := awsClient{
+		clients: map[config.AwsSourceConfig]awsServiceClients{
+			{}:          {ssmClient: textStrSsmClient, secsClient: textStrSecsClient},
+			otherSource: {ssmClient: otherSsmClient, secsClient: otherSecsClient},
+		},
+		parameterStoreValue: []config.AwsParameterStoreValueConfig{
+			{ValueConfig: config.ValueConfig{Name: "/name", Ref: stringPtr("ssmDefault")}},
+			{ValueConfig: config.ValueConfig{Name: "/name", Ref: stringPtr("ssmOther")}, AwsSourceConfig: otherSource},
+		},
+		secretsManagerValue: []config.AwsSecretsManagerValueConfig{
+			{ValueConfig: config.ValueConfig{Name: "name", Ref: stringPtr("secsDefault")}},
+			{ValueConfig: config.ValueConfig{Name: "name", Ref: stringPtr("secsOther")}, AwsSourceConfig: otherSource},
+		},
+	}
+	want := values.Values{"ssmDefault": "test", "ssmOther": "other", "secsDefault": "test", "secsOther": "other"}
+
+	got, err := c.GetValues(context.Background(), false)
+	if err != nil {
+		t.Fatalf("awsClient.GetValues() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("awsClient.GetValues() = %v, want %v", got, want)
+	}
+}
+
+func Test_awsClient_Lookup(t *testing.T) {
+	c := awsClient{
+		clients: map[config.AwsSourceConfig]awsServiceClients{
+			{}: {
+				ssmClient: mockSsmClient(func(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+					if !aws.ToBool(params.WithDecryption) {
+						return nil, errors.New("not decrypted")
+					}
+					switch aws.ToString(params.Name) {
+					case "/found":
+						return &ssm.GetParameterOutput{Parameter: &ssmTypes.Parameter{Value: stringPtr("parameter")}}, nil
+					case "/error":
+						return nil, errors.New("error")
+					}
+					return nil, &ssmTypes.ParameterNotFound{}
+				}),
+				secsClient: mockSecsClient(func(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error) {
+					switch aws.ToString(params.SecretId) {
+					case "found":
+						return &secs.GetSecretValueOutput{SecretString: stringPtr("secret")}, nil
+					case "binary":
+						return &secs.GetSecretValueOutput{SecretBinary: []byte("binary")}, nil
+					case "error":
+						return nil, errors.New("error")
+					}
+					return nil, &secsTypes.ResourceNotFoundException{}
+				}),
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		lookup  func(context.Context, string) (*string, error)
+		arg     string
+		want    *string
+		wantErr bool
+	}{
+		{name: "ok: parameter", lookup: c.LookupParameter, arg: "/found", want: stringPtr("parameter")},
+		{name: "ok: parameter not found", lookup: c.LookupParameter, arg: "/notfound", want: nil},
+		{name: "error: parameter", lookup: c.LookupParameter, arg: "/error", wantErr: true},
+		{name: "ok: secret", lookup: c.LookupSecret, arg: "found", want: stringPtr("secret")},
+		{name: "ok: binary secret", lookup: c.LookupSecret, arg: "binary", want: stringPtr("YmluYXJ5")},
+		{name: "ok: secret not found", lookup: c.LookupSecret, arg: "notfound", want: nil},
+		{name: "error: secret", lookup: c.LookupSecret, arg: "error", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.lookup(context.Background(), tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("awsClient.Lookup() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("awsClient.Lookup() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_awsSourceSdkConfig(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config")
+	if err := os.WriteFile(configFile, []byte("[profile with-region]\nregion = eu-west-1\n[profile without-region]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+
+	sdkConfig := &aws.Config{Region: "ap-northeast-1"}
+
+	type args struct {
+		ctx    context.Context
+		source config.AwsSourceConfig
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantRegion     string
+		wantAssumeRole bool
+		wantErr        bool
+	}{
+		{
+			name:       "ok: default",
+			args:       args{ctx: context.Background(), source: config.AwsSourceConfig{}},
+			wantRegion: "ap-northeast-1",
+		},
+		{
+			name:       "ok: region",
+			args:       args{ctx: context.Background(), source: config.AwsSourceConfig{Region: "us-east-1"}},
+			wantRegion: "us-east-1",
+		},
+		{
+			name:       "ok: profile with region",
+			args:       args{ctx: context.Background(), source: config.AwsSourceConfig{Profile: "with-region"}},
+			wantRegion: "eu-west-1",
+		},
+		{
+			name:       "ok: profile without region",
+			args:       args{ctx: context.Background(), source: config.AwsSourceConfig{Profile: "without-region"}},
+			wantRegion: "ap-northeast-1",
+		},
+		{
+			name:           "ok: assume role",
+			args:           args{ctx: context.Background(), source: config.AwsSourceConfig{RoleArn: "arn:aws:iam::012345678901:role/other", ExternalID: "id"}},
+			wantRegion:     "ap-northeast-1",
+			wantAssumeRole: true,
+		},
+		{
+			name:    "error: profile not found",
+			args:    args{ctx: context.Background(), source: config.AwsSourceConfig{Profile: "not-found"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := awsSourceSdkConfig(tt.args.ctx, sdkConfig, config.AwsEndpointsConfig{}, tt.args.source)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("awsSourceSdkConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Region != tt.wantRegion {
+				t.Errorf("awsSourceSdkConfig() region = %v, want %v", got.Region, tt.wantRegion)
+			}
+			gotAssumeRole := false
+			if c, ok := got.Credentials.(*aws.CredentialsCache); ok {
+				gotAssumeRole = c.IsCredentialsProvider((*stscreds.AssumeRoleProvider)(nil))
+			}
+			if gotAssumeRole != tt.wantAssumeRole {
+				t.Errorf("awsSourceSdkConfig() assume role = %v, want %v", gotAssumeRole, tt.wantAssumeRole)
+			}
+		})
+	}
+}
+
+func Test_batchGetSecretValue(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		client secsClient
+		ids    []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    map[string]awsValue
+		wantErr bool
+	}{
+		{
+			name: "ok: match by name and arn",
+			args: args{
+				ctx: context.Background(),
+				client: mockSecsBatchClient(func(ctx context.Context, params *secs.BatchGetSecretValueInput, optFns ...func(*secs.Options)) (*secs.BatchGetSecretValueOutput, error) {
+					return &secs.BatchGetSecretValueOutput{
+						SecretValues: []secsTypes.SecretValueEntry{
+							{Name: stringPtr("foo"), ARN: stringPtr("arn:aws:secretsmanager:ap-northeast-1:012345678901:secret:foo-AbCdEf"), SecretString: stringPtr("foo")},
+							{Name: stringPtr("bar"), ARN: stringPtr("arn:aws:secretsmanager:ap-northeast-1:012345678901:secret:bar-AbCdEf"), SecretString: stringPtr("bar")},
+						},
+					}, nil
+				}),
+				ids: []string{"foo", "arn:aws:secretsmanager:ap-northeast-1:012345678901:secret:bar-AbCdEf"},
+			},
+			want: map[string]awsValue{
+				"foo": {value: stringPtr("foo"), found: true},
+				"arn:aws:secretsmanager:ap-northeast-1:012345678901:secret:bar-AbCdEf": {value: stringPtr("bar"), found: true},
+			},
+		},
+		{
+			name: "ok: get unmatched secret one by one",
+			args: args{
+				ctx: context.Background(),
+				client: mockSecsBatchClient(func(ctx context.Context, params *secs.BatchGetSecretValueInput, optFns ...func(*secs.Options)) (*secs.BatchGetSecretValueOutput, error) {
+					return &secs.BatchGetSecretValueOutput{
+						SecretValues: []secsTypes.SecretValueEntry{
+							{Name: stringPtr("foo"), ARN: stringPtr("arn:aws:secretsmanager:ap-northeast-1:012345678901:secret:foo-AbCdEf"), SecretString: stringPtr("foo")},
+						},
+					}, nil
+				}),
+				ids: []string{"arn:aws:secretsmanager:ap-northeast-1:012345678901:secret:foo"},
+			},
+			want: map[string]awsValue{
+				"arn:aws:secretsmanager:ap-northeast-1:012345678901:secret:foo": {value: stringPtr("test"), found: true},
+			},
+		},
+		{
+			name: "ok: not found",
+			args: args{
+				ctx: context.Background(),
+				client: mockSecsBatchClient(func(ctx context.Context, params *secs.BatchGetSecretValueInput, optFns ...func(*secs.Options)) (*secs.BatchGetSecretValueOutput, error) {
+					return &secs.BatchGetSecretValueOutput{
+						Errors: []secsTypes.APIErrorType{
+							{SecretId: stringPtr("foo"), ErrorCode: stringPtr(secsErrorCodeNotFound)},
+						},
+					}, nil
+				}),
+				ids: []string{"foo"},
+			},
+			want: map[string]awsValue{},
+		},
+		{
+			name: "error: return another error code",
+			args: args{
+				ctx: context.Background(),
+				client: mockSecsBatchClient(func(ctx context.Context, params *secs.BatchGetSecretValueInput, optFns ...func(*secs.Options)) (*secs.BatchGetSecretValueOutput, error) {
+					return &secs.BatchGetSecretValueOutput{
+						Errors: []secsTypes.APIErrorType{
+							{SecretId: stringPtr("foo"), ErrorCode: stringPtr("DecryptionFailure")},
+						},
+					}, nil
+				}),
+				ids: []string{"foo"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: return error",
+			args: args{
+				ctx: context.Background(),
+				client: mockSecsBatchClient(func(ctx context.Context, params *secs.BatchGetSecretValueInput, optFns ...func(*secs.Options)) (*secs.BatchGetSecretValueOutput, error) {
+					return nil, &secsTypes.InternalServiceError{}
+				}),
+				ids: []string{"foo"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := batchGetSecretValue(tt.args.ctx, tt.args.client, tt.args.ids)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("batchGetSecretValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("batchGetSecretValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ssmClientWithCache_GetParameter(t *testing.T) {
+	var params = &ssm.GetParameterInput{Name: stringPtr("any")}
+	var textStrSsmClientWant = &ssm.GetParameterOutput{Parameter: &ssmTypes.Parameter{Value: stringPtr("test")}}
+
+	type fields struct {
+		client ssmClient
+		cache  cache.Cache
+	}
+	type args struct {
+		ctx    context.Context
+		params *ssm.GetParameterInput
+		optFns []func(*ssm.Options)
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    *ssm.GetParameterOutput
+		wantErr bool
+	}{
+		{
+			name: "ok: get from cache",
+			fields: fields{
+				client: nil,
+				cache:  mockCache{load: mockLoadFunc, save: mockSaveFunc},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: params,
+			},
+			want: &ssm.GetParameterOutput{Parameter: &ssmTypes.Parameter{Value: stringPtr("value")}},
+		},
+		{
+			name: "ok: get from cache(cache disabled)",
+			fields: fields{
+				client: textStrSsmClient,
+				cache:  nil,
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: &ssm.GetParameterInput{Name: stringPtr("key")},
+			},
+			want: textStrSsmClientWant,
+		},
+		{
+			name: "ok: get from client(no cache)",
+			fields: fields{
+				client: textStrSsmClient,
+				cache:  mockCache{load: mockLoadFuncNotFound, save: mockSaveFunc},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: params,
+			},
+			want: textStrSsmClientWant,
+		},
+		{
+			name: "ok: get from client(cache expired)",
+			fields: fields{
 				client: textStrSsmClient,
+				cache:  mockCache{load: mockLoadFuncExpired, save: mockSaveFunc},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: params,
+			},
+			want: textStrSsmClientWant,
+		},
+		{
+			name: "ok: get stale value from cache(throttling)",
+			fields: fields{
+				client: throttlingSsmClient,
+				cache:  mockCache{load: mockLoadFuncExpired, loadStale: mockLoadStaleFunc, save: mockSaveFunc},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: params,
+			},
+			want: &ssm.GetParameterOutput{Parameter: &ssmTypes.Parameter{Value: stringPtr("stale")}},
+		},
+		{
+			name: "error: stale value is not allowed",
+			fields: fields{
+				client: throttlingSsmClient,
+				cache:  mockCache{load: mockLoadFuncExpired, save: mockSaveFunc},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: params,
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: not transient error",
+			fields: fields{
+				client: mockSsmClient(func(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+					return nil, &ssmTypes.ParameterNotFound{}
+				}),
+				cache: mockCache{load: mockLoadFuncExpired, loadStale: mockLoadStaleFunc, save: mockSaveFunc},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: params,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := ssmClientWithCache{
+				client: tt.fields.client,
+				cache:  tt.fields.cache,
+			}
+			got, err := c.GetParameter(tt.args.ctx, tt.args.params, tt.args.optFns...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ssmClientWithCache.GetParameter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ssmClientWithCache.GetParameter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ssmClientWithCache_GetParametersByPath(t *testing.T) {
+	var params = &ssm.GetParametersByPathInput{Path: stringPtr("/app/prod"), Recursive: aws.Bool(true)}
+	var pagedSsmPathClientWant = &ssm.GetParametersByPathOutput{
+		Parameters: []ssmTypes.Parameter{
+			{Name: stringPtr("/app/prod/db/host"), Value: stringPtr("localhost")},
+			{Name: stringPtr("/app/prod/db/port"), Value: stringPtr("5432")},
+			{Name: stringPtr("/app/prod/name"), Value: stringPtr("app")},
+		},
+	}
+
+	type fields struct {
+		client ssmClient
+		cache  cache.Cache
+	}
+	type args struct {
+		ctx    context.Context
+		params *ssm.GetParametersByPathInput
+		optFns []func(*ssm.Options)
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    *ssm.GetParametersByPathOutput
+		wantErr bool
+	}{
+		{
+			name: "ok: get from cache",
+			fields: fields{
+				client: nil,
+				cache: mockCache{
+					load: func(_ context.Context, key string, decrypt bool) (*string, bool, error) {
+						if key != "path:/app/prod?recursive" {
+							return nil, true, nil
+						}
+						return stringPtr(`{"/app/prod/name":"cached"}`), false, nil
+					},
+					save: mockSaveFunc,
+				},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: params,
+			},
+			want: &ssm.GetParametersByPathOutput{
+				Parameters: []ssmTypes.Parameter{{Name: stringPtr("/app/prod/name"), Value: stringPtr("cached")}},
+			},
+		},
+		{
+			name: "ok: get first page from client(cache disabled)",
+			fields: fields{
+				client: pagedSsmPathClient,
+				cache:  nil,
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: params,
+			},
+			want: &ssm.GetParametersByPathOutput{
+				Parameters: []ssmTypes.Parameter{{Name: stringPtr("/app/prod/db/host"), Value: stringPtr("localhost")}},
+				NextToken:  stringPtr("next"),
+			},
+		},
+		{
+			name: "ok: get all pages from client(no cache)",
+			fields: fields{
+				client: pagedSsmPathClient,
+				cache:  mockCache{load: mockLoadFuncNotFound, save: mockSaveFunc},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: params,
+			},
+			want: pagedSsmPathClientWant,
+		},
+		{
+			name: "ok: get all pages from client(cache expired)",
+			fields: fields{
+				client: pagedSsmPathClient,
+				cache:  mockCache{load: mockLoadFuncExpired, save: mockSaveFunc},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: params,
+			},
+			want: pagedSsmPathClientWant,
+		},
+		{
+			name: "error: return error from client",
+			fields: fields{
+				client: mockSsmClient(nil),
 				cache:  mockCache{load: mockLoadFuncNotFound, save: mockSaveFunc},
 			},
 			args: args{
 				ctx:    context.Background(),
-				params: params,
+				params: params,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := ssmClientWithCache{
+				client: tt.fields.client,
+				cache:  tt.fields.cache,
+			}
+			got, err := c.GetParametersByPath(tt.args.ctx, tt.args.params, tt.args.optFns...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ssmClientWithCache.GetParametersByPath() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ssmClientWithCache.GetParametersByPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ssmClientWithCache_GetParameters(t *testing.T) {
+	// "cached" is in the cache and "other" is not.
+	partialCache := mockCache{
+		load: func(_ context.Context, key string, decrypt bool) (*string, bool, error) {
+			if key == "cached" {
+				return stringPtr("value"), false, nil
+			}
+			return nil, false, nil
+		},
+		save: mockSaveFunc,
+	}
+
+	type fields struct {
+		client ssmClient
+		cache  cache.Cache
+	}
+	type args struct {
+		ctx    context.Context
+		params *ssm.GetParametersInput
+		optFns []func(*ssm.Options)
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    *ssm.GetParametersOutput
+		wantErr bool
+	}{
+		{
+			name: "ok: get all from cache",
+			fields: fields{
+				client: nil,
+				cache:  mockCache{load: mockLoadFunc, save: mockSaveFunc},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: &ssm.GetParametersInput{Names: []string{"foo", "bar"}},
+			},
+			want: &ssm.GetParametersOutput{Parameters: []ssmTypes.Parameter{
+				{Name: stringPtr("foo"), Value: stringPtr("value")},
+				{Name: stringPtr("bar"), Value: stringPtr("value")},
+			}},
+		},
+		{
+			name: "ok: get from cache(cache disabled)",
+			fields: fields{
+				client: textStrSsmClient,
+				cache:  nil,
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: &ssm.GetParametersInput{Names: []string{"foo"}},
+			},
+			want: &ssm.GetParametersOutput{Parameters: []ssmTypes.Parameter{
+				{Name: stringPtr("foo"), Value: stringPtr("test")},
+			}},
+		},
+		{
+			name: "ok: get from cache and client",
+			fields: fields{
+				client: textStrSsmClient,
+				cache:  partialCache,
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: &ssm.GetParametersInput{Names: []string{"cached", "other"}},
+			},
+			want: &ssm.GetParametersOutput{Parameters: []ssmTypes.Parameter{
+				{Name: stringPtr("cached"), Value: stringPtr("value")},
+				{Name: stringPtr("other"), Value: stringPtr("test")},
+			}},
+		},
+		{
+			name: "ok: get from client(cache expired)",
+			fields: fields{
+				client: textStrSsmClient,
+				cache:  mockCache{load: mockLoadFuncExpired, save: mockSaveFunc},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: &ssm.GetParametersInput{Names: []string{"foo"}},
+			},
+			want: &ssm.GetParametersOutput{Parameters: []ssmTypes.Parameter{
+				{Name: stringPtr("foo"), Value: stringPtr("test")},
+			}},
+		},
+		{
+			name: "ok: cache with selector",
+			fields: fields{
+				client: mockSsmBatchClient(func(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersOutput, error) {
+					return &ssm.GetParametersOutput{Parameters: []ssmTypes.Parameter{
+						{Name: stringPtr("/name"), Selector: stringPtr(":3"), Value: stringPtr("test")},
+					}}, nil
+				}),
+				cache: mockCache{
+					load: mockLoadFuncNotFound,
+					save: func(_ context.Context, key string, value *string, encrypt bool) error {
+						if key != "/name:3" {
+							return fmt.Errorf("unexpected key: %s", key)
+						}
+						return nil
+					},
+				},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: &ssm.GetParametersInput{Names: []string{"/name:3"}},
+			},
+			want: &ssm.GetParametersOutput{Parameters: []ssmTypes.Parameter{
+				{Name: stringPtr("/name"), Selector: stringPtr(":3"), Value: stringPtr("test")},
+			}},
+		},
+		{
+			name: "error: return error from client",
+			fields: fields{
+				client: mockSsmClient(func(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+					return nil, &ssmTypes.InternalServerError{}
+				}),
+				cache: partialCache,
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: &ssm.GetParametersInput{Names: []string{"cached", "other"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ok: get stale values from cache(throttling)",
+			fields: fields{
+				client: throttlingSsmClient,
+				cache:  mockCache{load: partialCache.load, loadStale: mockLoadStaleFunc, save: mockSaveFunc},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: &ssm.GetParametersInput{Names: []string{"cached", "other"}},
+			},
+			want: &ssm.GetParametersOutput{Parameters: []ssmTypes.Parameter{
+				{Name: stringPtr("cached"), Value: stringPtr("value")},
+				{Name: stringPtr("other"), Value: stringPtr("stale")},
+			}},
+		},
+		{
+			name: "error: some stale values are not allowed",
+			fields: fields{
+				client: throttlingSsmClient,
+				cache: mockCache{
+					load: mockLoadFuncNotFound,
+					loadStale: func(_ context.Context, key string, decrypt bool) (*string, error) {
+						if key == "foo" {
+							return stringPtr("stale"), nil
+						}
+						return nil, nil
+					},
+					save: mockSaveFunc,
+				},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: &ssm.GetParametersInput{Names: []string{"foo", "bar"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := ssmClientWithCache{
+				client: tt.fields.client,
+				cache:  tt.fields.cache,
+			}
+			got, err := c.GetParameters(tt.args.ctx, tt.args.params, tt.args.optFns...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ssmClientWithCache.GetParameters() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ssmClientWithCache.GetParameters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_secsClientWithCache_BatchGetSecretValue(t *testing.T) {
+	// "cached" is in the cache and "other" is not.
+	partialCache := mockCache{
+		load: func(_ context.Context, key string, decrypt bool) (*string, bool, error) {
+			if key == "cached" {
+				return stringPtr("value"), false, nil
+			}
+			return nil, false, nil
+		},
+		save: mockSaveFunc,
+	}
+
+	type fields struct {
+		client secsClient
+		cache  cache.Cache
+	}
+	type args struct {
+		ctx    context.Context
+		params *secs.BatchGetSecretValueInput
+		optFns []func(*secs.Options)
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    *secs.BatchGetSecretValueOutput
+		wantErr bool
+	}{
+		{
+			name: "ok: get all from cache",
+			fields: fields{
+				client: nil,
+				cache:  mockCache{load: mockLoadFunc, save: mockSaveFunc},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: &secs.BatchGetSecretValueInput{SecretIdList: []string{"foo", "bar"}},
 			},
-			want: textStrSsmClientWant,
+			want: &secs.BatchGetSecretValueOutput{SecretValues: []secsTypes.SecretValueEntry{
+				{Name: stringPtr("foo"), SecretString: stringPtr("value")},
+				{Name: stringPtr("bar"), SecretString: stringPtr("value")},
+			}},
+		},
+		{
+			name: "ok: get from cache(cache disabled)",
+			fields: fields{
+				client: textStrSecsClient,
+				cache:  nil,
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: &secs.BatchGetSecretValueInput{SecretIdList: []string{"foo"}},
+			},
+			want: &secs.BatchGetSecretValueOutput{SecretValues: []secsTypes.SecretValueEntry{
+				{Name: stringPtr("foo"), SecretString: stringPtr("test")},
+			}},
+		},
+		{
+			name: "ok: get from cache and client",
+			fields: fields{
+				client: textStrSecsClient,
+				cache:  partialCache,
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: &secs.BatchGetSecretValueInput{SecretIdList: []string{"cached", "other"}},
+			},
+			want: &secs.BatchGetSecretValueOutput{SecretValues: []secsTypes.SecretValueEntry{
+				{Name: stringPtr("cached"), SecretString: stringPtr("value")},
+				{Name: stringPtr("other"), SecretString: stringPtr("test")},
+			}},
 		},
 		{
 			name: "ok: get from client(cache expired)",
 			fields: fields{
-				client: textStrSsmClient,
+				client: textStrSecsClient,
 				cache:  mockCache{load: mockLoadFuncExpired, save: mockSaveFunc},
 			},
 			args: args{
 				ctx:    context.Background(),
-				params: params,
+				params: &secs.BatchGetSecretValueInput{SecretIdList: []string{"foo"}},
 			},
-			want: textStrSsmClientWant,
+			want: &secs.BatchGetSecretValueOutput{SecretValues: []secsTypes.SecretValueEntry{
+				{Name: stringPtr("foo"), SecretString: stringPtr("test")},
+			}},
+		},
+		{
+			name: "error: return error from client",
+			fields: fields{
+				client: mockSecsClient(func(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error) {
+					return nil, &secsTypes.InternalServiceError{}
+				}),
+				cache: partialCache,
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: &secs.BatchGetSecretValueInput{SecretIdList: []string{"cached", "other"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ok: get stale values from cache(throttling)",
+			fields: fields{
+				client: throttlingSecsClient,
+				cache:  mockCache{load: partialCache.load, loadStale: mockLoadStaleFunc, save: mockSaveFunc},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: &secs.BatchGetSecretValueInput{SecretIdList: []string{"cached", "other"}},
+			},
+			want: &secs.BatchGetSecretValueOutput{SecretValues: []secsTypes.SecretValueEntry{
+				{Name: stringPtr("cached"), SecretString: stringPtr("value")},
+				{Name: stringPtr("other"), SecretString: stringPtr("stale")},
+			}},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c := ssmClientWithCache{
+			c := secsClientWithCache{
 				client: tt.fields.client,
 				cache:  tt.fields.cache,
 			}
-			got, err := c.GetParameter(tt.args.ctx, tt.args.params, tt.args.optFns...)
+			got, err := c.BatchGetSecretValue(tt.args.ctx, tt.args.params, tt.args.optFns...)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("ssmClientWithCache.GetParameter() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("secsClientWithCache.BatchGetSecretValue() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("ssmClientWithCache.GetParameter() = %v, want %v", got, tt.want)
+				t.Errorf("secsClientWithCache.BatchGetSecretValue() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -425,6 +1703,30 @@ func Test_secsClientWithCache_GetSecretValue(t *testing.T) {
 			},
 			want: textStrSecsClientWant,
 		},
+		{
+			name: "ok: get stale value from cache(throttling)",
+			fields: fields{
+				client: throttlingSecsClient,
+				cache:  mockCache{load: mockLoadFuncExpired, loadStale: mockLoadStaleFunc, save: mockSaveFunc},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: params,
+			},
+			want: &secs.GetSecretValueOutput{SecretString: stringPtr("stale")},
+		},
+		{
+			name: "error: stale value is not allowed",
+			fields: fields{
+				client: throttlingSecsClient,
+				cache:  mockCache{load: mockLoadFuncExpired, save: mockSaveFunc},
+			},
+			args: args{
+				ctx:    context.Background(),
+				params: params,
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -444,9 +1746,378 @@ func Test_secsClientWithCache_GetSecretValue(t *testing.T) {
 	}
 }
 
+func Test_secsClientWithCache_GetSecretValue_binary(t *testing.T) {
+	// binary secret is cached as base64 with the suffix
+	cached := map[string]string{}
+	c := secsClientWithCache{
+		client: mockSecsClient(func(ctx context.Context, params *secs.GetSecretValueInput, optFns ...func(*secs.Options)) (*secs.GetSecretValueOutput, error) {
+			return &secs.GetSecretValueOutput{SecretBinary: []byte("binary")}, nil
+		}),
+		cache: mockCache{
+			load: func(_ context.Context, key string, decrypt bool) (*string, bool, error) {
+				if value, ok := cached[key]; ok {
+					return &value, false, nil
+				}
+				return nil, false, nil
+			},
+			save: func(_ context.Context, key string, value *string, encrypt bool) error {
+				cached[key] = *value
+				return nil
+			},
+		},
+	}
+	params := &secs.GetSecretValueInput{SecretId: stringPtr("name"), VersionStage: stringPtr("AWSPREVIOUS")}
+	want := &secs.GetSecretValueOutput{SecretBinary: []byte("binary")}
+
+	for range 2 {
+		got, err := c.GetSecretValue(context.Background(), params)
+		if err != nil {
+			t.Fatalf("secsClientWithCache.GetSecretValue() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("secsClientWithCache.GetSecretValue() = %v, want %v", got, want)
+		}
+	}
+	if wantCached := map[string]string{"name?version_stage=AWSPREVIOUS#binary": "YmluYXJ5"}; !reflect.DeepEqual(cached, wantCached) {
+		t.Errorf("cached = %v, want %v", cached, wantCached)
+	}
+}
+
+func Test_secsCacheKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *secs.GetSecretValueInput
+		want   string
+	}{
+		{
+			name:   "ok: no version",
+			params: &secs.GetSecretValueInput{SecretId: stringPtr("name")},
+			want:   "name",
+		},
+		{
+			name:   "ok: version stage",
+			params: &secs.GetSecretValueInput{SecretId: stringPtr("name"), VersionStage: stringPtr("AWSPREVIOUS")},
+			want:   "name?version_stage=AWSPREVIOUS",
+		},
+		{
+			name:   "ok: version id and stage",
+			params: &secs.GetSecretValueInput{SecretId: stringPtr("name"), VersionId: stringPtr("id"), VersionStage: stringPtr("AWSCURRENT")},
+			want:   "name?version_id=id&version_stage=AWSCURRENT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := secsCacheKey(tt.params); got != tt.want {
+				t.Errorf("secsCacheKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newFakeAwsServer returns a fake server of Parameter Store, Secrets Manager and STS, and the
+// number of STS calls. Every parameter and secret exists and its value is the name with the
+// service prefix.
+func Test_isAwsTransientError(t *testing.T) {
+	responseError := func(status int) error {
+		return &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+				Err:      errors.New("response error"),
+			},
+		}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "throttling",
+			err:  GettingValueError("name", &smithy.GenericAPIError{Code: "ThrottlingException"}),
+			want: true,
+		},
+		{
+			name: "too many requests",
+			err:  responseError(http.StatusTooManyRequests),
+			want: true,
+		},
+		{
+			name: "server error",
+			err:  responseError(http.StatusServiceUnavailable),
+			want: true,
+		},
+		{
+			name: "network error",
+			err:  &smithyhttp.RequestSendError{Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}},
+			want: true,
+		},
+		{
+			name: "client error",
+			err:  responseError(http.StatusBadRequest),
+			want: false,
+		},
+		{
+			name: "not found",
+			err:  &ssmTypes.ParameterNotFound{},
+			want: false,
+		},
+		{
+			name: "other error",
+			err:  errors.New("error"),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAwsTransientError(tt.err); got != tt.want {
+				t.Errorf("isAwsTransientError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func newFakeAwsServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	stsCalls := &atomic.Int32{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var output any
+		switch r.Header.Get("X-Amz-Target") {
+		case "AmazonSSM.GetParameters":
+			var input struct{ Names []string }
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			parameters := []map[string]string{}
+			for _, name := range input.Names {
+				parameters = append(parameters, map[string]string{"Name": name, "Value": "ssm:" + name})
+			}
+			output = map[string]any{"Parameters": parameters, "InvalidParameters": []string{}}
+		case "secretsmanager.BatchGetSecretValue":
+			var input struct{ SecretIdList []string }
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			secrets := []map[string]string{}
+			for _, id := range input.SecretIdList {
+				secrets = append(secrets, map[string]string{"Name": id, "SecretString": "secs:" + id})
+			}
+			output = map[string]any{"SecretValues": secrets, "Errors": []any{}}
+		default:
+			// STS uses the query protocol.
+			if err := r.ParseForm(); err != nil || r.URL.Path != "/" || r.Form.Get("Action") != "GetCallerIdentity" {
+				http.Error(w, "unknown action", http.StatusBadRequest)
+				return
+			}
+			stsCalls.Add(1)
+			w.Header().Set("Content-Type", "text/xml")
+			fmt.Fprint(w, `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">`+
+				`<GetCallerIdentityResult><Arn>arn:aws:iam::012345678901:user/test</Arn><UserId>TEST</UserId><Account>012345678901</Account></GetCallerIdentityResult>`+
+				`<ResponseMetadata><RequestId>test</RequestId></ResponseMetadata></GetCallerIdentityResponse>`)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		if err := json.NewEncoder(w).Encode(output); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, stsCalls
+}
+
+func Test_newAwsClient_endpoints(t *testing.T) {
+	server, stsCalls := newFakeAwsServer(t)
+
+	cfg := &config.Config{
+		Global: config.GlobalConfig{
+			EnableCache: true,
+			Cache:       config.CacheConfig{Type: config.CacheTypeMemory},
+		},
+		Aws: &config.AwsConfig{
+			ParameterStoreValues: []config.AwsParameterStoreValueConfig{
+				{ValueConfig: config.ValueConfig{Name: "/name"}},
+			},
+			SecretsManagerValues: []config.AwsSecretsManagerValueConfig{
+				{ValueConfig: config.ValueConfig{Name: "name"}},
+			},
+			Endpoints: config.AwsEndpointsConfig{
+				Ssm:            server.URL,
+				SecretsManager: server.URL,
+				Sts:            server.URL,
+			},
+			SdkConfig: &aws.Config{
+				Region:      "us-east-1",
+				Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+			},
+		},
+	}
+
+	c, err := newAwsClient(cfg)
+	if err != nil {
+		t.Fatalf("newAwsClient() error = %v", err)
+	}
+	// the account ID is looked up lazily
+	if got := stsCalls.Load(); got != 0 {
+		t.Errorf("STS calls before GetValues = %d, want 0", got)
+	}
+
+	for range 2 {
+		got, err := c.GetValues(context.Background(), false)
+		if err != nil {
+			t.Fatalf("awsClient.GetValues() error = %v", err)
+		}
+		if want := (values.Values{"/name": "ssm:/name", "name": "secs:name"}); !reflect.DeepEqual(got, want) {
+			t.Errorf("awsClient.GetValues() = %v, want %v", got, want)
+		}
+	}
+	if got := stsCalls.Load(); got != 1 {
+		t.Errorf("STS calls = %d, want 1", got)
+	}
+}
+
+func Test_newAwsClient_cacheNamespace(t *testing.T) {
+	server, stsCalls := newFakeAwsServer(t)
+
+	cfg := &config.Config{
+		Global: config.GlobalConfig{
+			EnableCache: true,
+			Cache:       config.CacheConfig{Type: config.CacheTypeMemory},
+		},
+		Aws: &config.AwsConfig{
+			ParameterStoreValues: []config.AwsParameterStoreValueConfig{
+				{ValueConfig: config.ValueConfig{Name: "/name"}},
+			},
+			Endpoints:      config.AwsEndpointsConfig{Ssm: server.URL, Sts: server.URL},
+			CacheNamespace: "workload",
+			SdkConfig: &aws.Config{
+				Region:      "us-east-1",
+				Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+			},
+		},
+	}
+
+	c, err := newAwsClient(cfg)
+	if err != nil {
+		t.Fatalf("newAwsClient() error = %v", err)
+	}
+	if _, err := c.GetValues(context.Background(), false); err != nil {
+		t.Fatalf("awsClient.GetValues() error = %v", err)
+	}
+	if got := stsCalls.Load(); got != 0 {
+		t.Errorf("STS calls = %d, want 0", got)
+	}
+}
+
+func Test_awsCacheNamespace(t *testing.T) {
+	type args struct {
+		cfg    *config.AwsConfig
+		source config.AwsSourceConfig
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "ok: source namespace",
+			args: args{
+				cfg:    &config.AwsConfig{CacheNamespace: "global"},
+				source: config.AwsSourceConfig{CacheNamespace: "source", RoleArn: "arn:aws:iam::012345678901:role/test"},
+			},
+			want: "source",
+		},
+		{
+			name: "ok: role arn",
+			args: args{
+				cfg:    &config.AwsConfig{CacheNamespace: "global"},
+				source: config.AwsSourceConfig{RoleArn: "arn:aws:iam::012345678901:role/test", Profile: "test"},
+			},
+			want: "012345678901",
+		},
+		{
+			name: "ok: profile",
+			args: args{
+				cfg:    &config.AwsConfig{CacheNamespace: "global"},
+				source: config.AwsSourceConfig{Profile: "test"},
+			},
+			want: "profile:test",
+		},
+		{
+			name: "ok: global namespace",
+			args: args{
+				cfg:    &config.AwsConfig{CacheNamespace: "global"},
+				source: config.AwsSourceConfig{Region: "us-east-1"},
+			},
+			want: "global",
+		},
+		{
+			name: "ok: account id is looked up",
+			args: args{
+				cfg:    &config.AwsConfig{},
+				source: config.AwsSourceConfig{RoleArn: "invalid"},
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := awsCacheNamespace(tt.args.cfg, tt.args.source); got != tt.want {
+				t.Errorf("awsCacheNamespace() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_awsLazyCache(t *testing.T) {
+	calls := 0
+	c := &awsLazyCache{newCache: func(ctx context.Context) (cache.Cache, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("failed")
+		}
+		return mockCache{load: mockLoadFunc, save: mockSaveFunc}, nil
+	}}
+
+	// creating cache is retried after an error
+	if _, _, err := c.Load(context.Background(), "key", false); err == nil {
+		t.Errorf("awsLazyCache.Load() error = nil, want error")
+	}
+	for range 2 {
+		got, _, err := c.Load(context.Background(), "key", false)
+		if err != nil {
+			t.Fatalf("awsLazyCache.Load() error = %v", err)
+		}
+		if *got != "value" {
+			t.Errorf("awsLazyCache.Load() = %v, want %v", *got, "value")
+		}
+	}
+	if err := c.Save(context.Background(), "key", stringPtr("value"), false); err != nil {
+		t.Errorf("awsLazyCache.Save() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls of newCache = %d, want 2", calls)
+	}
+}
+
 func Test_getAwsAccountId(t *testing.T) {
+	server, _ := newFakeAwsServer(t)
+	sdkConfig := aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+	}
+
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
 	type args struct {
-		sdkConfig *aws.Config
+		ctx       context.Context
+		stsClient *sts.Client
 	}
 	tests := []struct {
 		name    string
@@ -454,11 +2125,34 @@ func Test_getAwsAccountId(t *testing.T) {
 		want    string
 		wantErr bool
 	}{
-		// TODO: Add test cases.
+		{
+			name: "ok",
+			args: args{
+				ctx:       context.Background(),
+				stsClient: newStsClient(sdkConfig, config.AwsEndpointsConfig{Sts: server.URL}),
+			},
+			want: "012345678901",
+		},
+		{
+			name: "error: request failed",
+			args: args{
+				ctx:       context.Background(),
+				stsClient: newStsClient(sdkConfig, config.AwsEndpointsConfig{Sts: server.URL + "/not-found"}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: context canceled",
+			args: args{
+				ctx:       canceledCtx,
+				stsClient: newStsClient(sdkConfig, config.AwsEndpointsConfig{Sts: server.URL}),
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := getAwsAccountId(tt.args.sdkConfig)
+			got, err := getAwsAccountId(tt.args.ctx, tt.args.stsClient)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getAwsAccountId() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -469,3 +2163,73 @@ func Test_getAwsAccountId(t *testing.T) {
 		})
 	}
 }
+
+func Test_lookupAwsAccountId(t *testing.T) {
+	server, _ := newFakeAwsServer(t)
+	endpoints := config.AwsEndpointsConfig{Sts: server.URL}
+	failingEndpoints := config.AwsEndpointsConfig{Sts: server.URL + "/not-found"}
+	sdkConfig := func(accessKeyID, accountID string) aws.Config {
+		return aws.Config{
+			Region: "us-east-1",
+			Credentials: credentials.StaticCredentialsProvider{Value: aws.Credentials{
+				AccessKeyID: accessKeyID, SecretAccessKey: "SECRET", AccountID: accountID,
+			}},
+		}
+	}
+
+	accounts, err := cache.New(config.CacheConfig{}, cache.WithCacheKeys("aws", "accounts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		sdkConfig aws.Config
+		endpoints config.AwsEndpointsConfig
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "error: looking up failed and not saved",
+			sdkConfig: sdkConfig("AKID", ""),
+			endpoints: failingEndpoints,
+			wantErr:   true,
+		},
+		{
+			name:      "ok: looked up by sts",
+			sdkConfig: sdkConfig("AKID", ""),
+			endpoints: endpoints,
+			want:      "012345678901",
+		},
+		{
+			name:      "ok: saved one is used if looking up failed",
+			sdkConfig: sdkConfig("AKID", ""),
+			endpoints: failingEndpoints,
+			want:      "012345678901",
+		},
+		{
+			name:      "error: saved one of other credentials is not used",
+			sdkConfig: sdkConfig("OTHER", ""),
+			endpoints: failingEndpoints,
+			wantErr:   true,
+		},
+		{
+			name:      "ok: account id of credentials is used without sts",
+			sdkConfig: sdkConfig("OTHER", "111111111111"),
+			endpoints: failingEndpoints,
+			want:      "111111111111",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lookupAwsAccountId(context.Background(), tt.sdkConfig, tt.endpoints, accounts, time.Second)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("lookupAwsAccountId() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("lookupAwsAccountId() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/dwango/yashiro/pkg/config"
@@ -29,6 +30,9 @@ import (
 var (
 	ErrInvalidCacheType = errors.New("invalid cache type")
 	ErrCacheProcessing  = errors.New("cache processing error")
+	ErrInsecureFile     = errors.New("insecure cache file")
+	ErrInvalidKeySource = errors.New("invalid key source")
+	ErrUndecryptable    = errors.New("cached value cannot be decrypted with the key")
 )
 
 type Cache interface {
@@ -36,19 +40,23 @@ type Cache interface {
 	// returned a string is nil and expired is true.
 	Load(ctx context.Context, key string, decrypt bool) (*string, bool, error)
 
+	// LoadStale returns cached string by using the key even if it is expired, as long as the stale_if_error
+	// policy allows it. If a cache is empty, too old or the policy is disabled, returned a string is nil.
+	LoadStale(ctx context.Context, key string, decrypt bool) (*string, error)
+
 	// Save saves value to cache. If encrypt is true, value is encrypted before saving.
 	Save(ctx context.Context, key string, value *string, encrypt bool) error
 }
 
 func New(cfg config.CacheConfig, options ...Option) (Cache, error) {
-	expireDuration := config.DefaultExpireDuration
-	if cfg.ExpireDuration != 0 {
-		expireDuration = time.Duration(cfg.ExpireDuration)
+	expireDuration := cacheExpireDuration(cfg)
+	if staleMaxAge := cacheStaleMaxAge(cfg); staleMaxAge != 0 {
+		options = append(slices.Clip(options), WithStaleMaxAge(staleMaxAge))
 	}
 
 	switch cfg.Type {
 	case config.CacheTypeUnspecified, config.CacheTypeMemory:
-		return newMemoryCache(expireDuration)
+		return newMemoryCache(expireDuration, options...)
 	case config.CacheTypeFile:
 		return newFileCache(cfg.File, expireDuration, options...)
 	default:
@@ -56,6 +64,33 @@ func New(cfg config.CacheConfig, options ...Option) (Cache, error) {
 	}
 }
 
+// cacheExpireDuration returns how long cached values are used.
+func cacheExpireDuration(cfg config.CacheConfig) time.Duration {
+	if cfg.ExpireDuration != 0 {
+		return time.Duration(cfg.ExpireDuration)
+	}
+
+	return config.DefaultExpireDuration
+}
+
+// cacheStaleMaxAge returns how long expired values can be used by stale_if_error policy. If the
+// policy is disabled, it returns 0.
+func cacheStaleMaxAge(cfg config.CacheConfig) time.Duration {
+	if !cfg.StaleIfError.Enabled {
+		return 0
+	}
+	if cfg.StaleIfError.MaxAge != 0 {
+		return time.Duration(cfg.StaleIfError.MaxAge)
+	}
+
+	return config.DefaultStaleIfErrorMaxAge
+}
+
+// isStale reports whether a value saved at saveTime can be used after it expires.
+func isStale(saveTime time.Time, expireDuration, staleMaxAge time.Duration) bool {
+	return staleMaxAge > 0 && time.Since(saveTime) <= expireDuration+staleMaxAge
+}
+
 func keyToHex(key string) string {
 	return hex.EncodeToString([]byte(key))
 }
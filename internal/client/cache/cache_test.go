@@ -44,6 +44,50 @@ func TestNew(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "ok: memory cache with stale_if_error",
+			args: args{
+				cfg: config.CacheConfig{
+					Type:         config.CacheTypeMemory,
+					StaleIfError: config.StaleIfErrorConfig{Enabled: true},
+				},
+			},
+			want: &memoryCache{
+				caches:         make(map[string]*cacheData),
+				expireDuration: config.DefaultExpireDuration,
+				staleMaxAge:    config.DefaultStaleIfErrorMaxAge,
+				keyPrefix:      "_",
+			},
+		},
+		{
+			name: "ok: memory cache with max age of stale_if_error",
+			args: args{
+				cfg: config.CacheConfig{
+					Type:         config.CacheTypeMemory,
+					StaleIfError: config.StaleIfErrorConfig{Enabled: true, MaxAge: config.Duration(time.Hour)},
+				},
+			},
+			want: &memoryCache{
+				caches:         make(map[string]*cacheData),
+				expireDuration: config.DefaultExpireDuration,
+				staleMaxAge:    time.Hour,
+				keyPrefix:      "_",
+			},
+		},
+		{
+			name: "ok: memory cache without stale_if_error",
+			args: args{
+				cfg: config.CacheConfig{
+					Type:         config.CacheTypeMemory,
+					StaleIfError: config.StaleIfErrorConfig{Enabled: false, MaxAge: config.Duration(time.Hour)},
+				},
+			},
+			want: &memoryCache{
+				caches:         make(map[string]*cacheData),
+				expireDuration: config.DefaultExpireDuration,
+				keyPrefix:      "_",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -17,14 +17,20 @@
 package cache
 
 import (
+	"bytes"
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dwango/yashiro/pkg/config"
@@ -36,80 +42,163 @@ const (
 	keyHashFileName = "keyHash"
 )
 
+// Cache files start with the header of the magic, the format version, the mode and the time when
+// the value was saved. Files without the header of the current version are written by old
+// versions, and are ignored and overwritten as not cached.
+const (
+	cacheFileMagic   = "YSRC"
+	cacheFileVersion = 2
+	// cacheFileHeaderLen is the length of the header. The save time is Unix time in nanoseconds
+	// encoded as big endian.
+	cacheFileHeaderLen = len(cacheFileMagic) + 2 + 8
+
+	// cacheFileModePlain is a value stored as plain text with the authentication tag.
+	cacheFileModePlain byte = 0
+	// cacheFileModeEncrypted is a value encrypted by AEAD.
+	cacheFileModeEncrypted byte = 1
+)
+
 var defaultCacheBasePath string
 
+// errTruncatedFile is an error of a file which does not end with the newline written by writeToFile,
+// e.g. an empty file left by an interrupted write of old versions.
+var errTruncatedFile = errors.New("file is truncated")
+
 type fileCache struct {
+	mu             sync.RWMutex // guards cache files against concurrent Load and Save
 	cachePath      string
-	cipherBlock    cipher.Block
+	aead           cipher.AEAD
+	keySource      keySource
 	expireDuration time.Duration
+	staleMaxAge    time.Duration
 	filenamePrefix string
 }
 
 func newFileCache(cfg config.FileCacheConfig, expireDuration time.Duration, options ...Option) (Cache, error) {
-	opts := defaultOpts
+	opts := defaultOpts()
 	for _, o := range options {
 		o(opts)
 	}
 
-	cachePath := defaultCacheBasePath
-	if len(cfg.CachePath) != 0 {
-		cachePath = cfg.CachePath
-	}
+	cachePath := fileCachePath(cfg)
 	filenamePrefix := keyToHex(strings.Join(opts.CacheKeys, "_")) + "_"
 
 	fc := &fileCache{
 		cachePath:      cachePath,
 		expireDuration: expireDuration,
+		staleMaxAge:    opts.StaleMaxAge,
 		filenamePrefix: filenamePrefix,
 	}
 
-	// create cache directory
-	if err := os.MkdirAll(fc.cachePath, 0777); err != nil {
+	// create cache directory, which must be accessible only by the current user
+	if err := os.MkdirAll(fc.cachePath, 0700); err != nil {
 		return nil, cacheProcessingError("failed to create cache directory", err)
 	}
+	dirInfo, err := os.Stat(fc.cachePath)
+	if err != nil {
+		return nil, cacheProcessingError("failed to get cache directory", err)
+	}
+	if err := checkFileSecurity(fc.cachePath, dirInfo); err != nil {
+		return nil, err
+	}
 
 	// read or create key
-	key, err := fc.readOrCreateKey()
+	fc.keySource, err = newKeySource(cfg.Key, fc)
+	if err != nil {
+		return nil, err
+	}
+	key, err := fc.keySource.key(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	fc.aead, err = newAEAD(key)
 	if err != nil {
 		return nil, err
 	}
 
-	fc.cipherBlock, err = aes.NewCipher(key)
+	return fc, nil
+}
+
+// fileCachePath returns the cache directory.
+func fileCachePath(cfg config.FileCacheConfig) string {
+	if len(cfg.CachePath) != 0 {
+		return cfg.CachePath
+	}
+
+	return defaultCacheBasePath
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, cacheProcessingError("failed to create cipher block", err)
 	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, cacheProcessingError("failed to create AEAD", err)
+	}
 
-	return fc, nil
+	return aead, nil
 }
 
-// Load implements Cache.
+// Load implements Cache. Files which are written by old versions, or fail authentication, e.g.
+// tampered or moved from another key, are treated as not cached.
 func (f *fileCache) Load(_ context.Context, key string, decrypt bool) (*string, bool, error) {
+	value, saveTime, err := f.load(key, decrypt)
+	if value == nil || err != nil {
+		return nil, false, err
+	}
+	// check if cache is expired
+	expired := time.Since(saveTime) > f.expireDuration
+
+	return value, expired, nil
+}
+
+// LoadStale implements Cache.
+func (f *fileCache) LoadStale(_ context.Context, key string, decrypt bool) (*string, error) {
+	if f.staleMaxAge <= 0 {
+		return nil, nil
+	}
+
+	value, saveTime, err := f.load(key, decrypt)
+	if value == nil || err != nil || !isStale(saveTime, f.expireDuration, f.staleMaxAge) {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// load returns the cached value and the time when it was saved. The save time is stored in the
+// authenticated header, not the modification time of the file. If the cache file is not found or
+// invalid, it returns nil.
+func (f *fileCache) load(key string, decrypt bool) (*string, time.Time, error) {
 	filename := keyToHex(key)
 
-	fInfo, err := f.getFileInfo(filename, false)
-	if err != nil {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if _, err := f.getFileInfo(filename, false); err != nil {
 		// cache file not found
-		return nil, false, nil
+		return nil, time.Time{}, nil
 	}
-	// check if cache is expired
-	expired := time.Since(fInfo.ModTime().Local()) > f.expireDuration
 
-	valueByte, err := f.readFile(filename, false)
-	if err != nil {
-		return nil, false, err
+	data, err := f.readFile(filename, false)
+	if errors.Is(err, errTruncatedFile) {
+		// broken cache file is treated as not cached
+		return nil, time.Time{}, nil
 	}
-	if !decrypt {
-		value := string(valueByte)
-		return &value, expired, nil
+	if err != nil {
+		return nil, time.Time{}, err
 	}
 
-	valueByte, err = f.decryptCache(valueByte)
-	if err != nil {
-		return nil, false, err
+	valueByte, encrypted, saveTime, ok := f.openCache(filename, data)
+	// A sensitive value must not be loaded from a plain text file.
+	if !ok || (decrypt && !encrypted) {
+		return nil, time.Time{}, nil
 	}
 	value := string(valueByte)
 
-	return &value, expired, nil
+	return &value, saveTime, nil
 }
 
 // Save implements Cache.
@@ -119,17 +208,15 @@ func (f *fileCache) Save(_ context.Context, key string, value *string, encrypt b
 	}
 
 	filename := keyToHex(key)
-	valueByte := []byte(*value)
-
-	if encrypt {
-		var err error
-		valueByte, err = f.encryptCache(valueByte)
-		if err != nil {
-			return err
-		}
+	data, err := f.sealCache(filename, []byte(*value), encrypt, time.Now())
+	if err != nil {
+		return err
 	}
 
-	if err := f.writeToFile(filename, valueByte, false); err != nil {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.writeToFile(filename, data, false); err != nil {
 		return err
 	}
 
@@ -137,80 +224,227 @@ func (f *fileCache) Save(_ context.Context, key string, value *string, encrypt b
 }
 
 func (f *fileCache) readOrCreateKey() ([]byte, error) {
-	var key []byte
-	// check key file exists
-	if _, err := f.getFileInfo(keyFileName, false); err != nil {
-		key = make([]byte, 32)
-
-		// create key file
-		if _, err := rand.Read(key); err != nil {
-			return nil, cacheProcessingError("failed to create key", err)
+	key, err := f.readSecureFile(keyFileName, false)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f.createKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keyHash, err := f.readSecureFile(keyHashFileName, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// check key is not tampered
+	if err := bcrypt.CompareHashAndPassword(keyHash, key); err != nil {
+		return nil, cacheProcessingError("key is tampered", err)
+	}
+
+	return key, nil
+}
+
+// cachedFileValue is a value of a cache file opened with the current key.
+type cachedFileValue struct {
+	filename  string
+	value     []byte
+	encrypted bool
+	saveTime  time.Time
+}
+
+// openValues returns the cached values of keys opened with the current key. Truncated files are
+// removed, but a file which fails authentication is an error, because the key may be wrong.
+func (f *fileCache) openValues(keys []string) ([]cachedFileValue, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values := make([]cachedFileValue, 0, len(keys))
+	for _, key := range keys {
+		filename := keyToHex(key)
+		if _, err := f.getFileInfo(filename, false); err != nil {
+			continue
+		}
+		data, err := f.readFile(filename, false)
+		if errors.Is(err, errTruncatedFile) {
+			if err := os.Remove(filepath.Join(f.cachePath, f.filenamePrefix+filename)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, cacheProcessingError("failed to remove file", err)
+			}
+			continue
 		}
-		if err := f.writeToFile(keyFileName, key, false); err != nil {
+		if err != nil {
 			return nil, err
 		}
 
-		// hashing key
-		keyHash, err := bcrypt.GenerateFromPassword(key, 5)
+		value, encrypted, saveTime, ok := f.openCache(filename, data)
+		if !ok {
+			return nil, fmt.Errorf("%w: '%s'", ErrUndecryptable, key)
+		}
+		values = append(values, cachedFileValue{filename: filename, value: value, encrypted: encrypted, saveTime: saveTime})
+	}
+
+	return values, nil
+}
+
+// rotateKey replaces the key with a new key, and encrypts values again with it. The save times
+// are kept, so that the values expire as before.
+func (f *fileCache) rotateKey(ctx context.Context, values []cachedFileValue) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key, err := f.keySource.newKey(ctx)
+	if err != nil {
+		return err
+	}
+	f.aead, err = newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range values {
+		data, err := f.sealCache(v.filename, v.value, v.encrypted, v.saveTime)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		if err := f.writeToFile(keyHashFileName, keyHash, true); err != nil {
-			return nil, err
+		if err := f.writeToFile(v.filename, data, false); err != nil {
+			return err
 		}
+	}
+
+	return nil
+}
+
+// createKey creates a new key file and the hash of it. The existing key file is replaced.
+func (f *fileCache) createKey() ([]byte, error) {
+	key := make([]byte, keySize)
 
-		return key, nil
+	// create key file
+	if _, err := rand.Read(key); err != nil {
+		return nil, cacheProcessingError("failed to create key", err)
+	}
+	if err := f.writeToFile(keyFileName, key, false); err != nil {
+		return nil, err
 	}
 
-	var err error
-	// read key file
-	key, err = f.readFile(keyFileName, false)
+	// hashing key
+	keyHash, err := bcrypt.GenerateFromPassword(key, 5)
 	if err != nil {
 		return nil, err
 	}
+	if err := f.writeToFile(keyHashFileName, keyHash, true); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
 
-	// read key hash file
-	keyHash, err := f.readFile(keyHashFileName, true)
+// readOrCreateFile returns the content of the file. If the file does not exist, the content is
+// created by create and written to the file.
+func (f *fileCache) readOrCreateFile(filename string, create func() ([]byte, error)) ([]byte, error) {
+	if _, err := f.getFileInfo(filename, false); err == nil {
+		return f.readFile(filename, false)
+	}
+
+	data, err := create()
 	if err != nil {
 		return nil, err
 	}
+	if err := f.writeToFile(filename, data, false); err != nil {
+		return nil, err
+	}
 
-	// check key is not tampered
-	if err := bcrypt.CompareHashAndPassword(keyHash, key); err != nil {
-		return nil, cacheProcessingError("key is tampered", err)
+	return data, nil
+}
+
+// sealCache returns the content of a cache file: the header, the nonce and the sealed value. If
+// encrypt is true, the value is encrypted. Otherwise, the value is stored as plain text after the
+// authentication tag. The header including saveTime and the cache file name are authenticated as
+// associated data.
+func (f *fileCache) sealCache(filename string, plainText []byte, encrypt bool, saveTime time.Time) ([]byte, error) {
+	mode := cacheFileModePlain
+	if encrypt {
+		mode = cacheFileModeEncrypted
 	}
+	header := cacheFileHeader(mode, saveTime)
 
-	return key, nil
+	data := make([]byte, 0, len(header)+f.aead.NonceSize()+f.aead.Overhead()+len(plainText))
+	data = append(data, header...)
+	nonce := data[len(header) : len(header)+f.aead.NonceSize()]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, cacheProcessingError("failed to create nonce", err)
+	}
+	data = data[:len(header)+len(nonce)]
+
+	ad := f.additionalData(header, filename)
+	if encrypt {
+		return f.aead.Seal(data, nonce, plainText, ad), nil
+	}
+
+	data = f.aead.Seal(data, nonce, nil, append(ad, plainText...))
+	return append(data, plainText...), nil
 }
 
-func (f *fileCache) decryptCache(cipherText []byte) ([]byte, error) {
-	if len(cipherText) < aes.BlockSize {
-		return nil, cacheProcessingError("cipher text is too short", nil)
+// openCache returns the value of a cache file, whether it is encrypted and the time when it was
+// saved. If the file is not in the current format or fails authentication, ok is false.
+func (f *fileCache) openCache(filename string, data []byte) (value []byte, encrypted bool, saveTime time.Time, ok bool) {
+	mode, saveTime, ok := parseCacheFileHeader(data)
+	if !ok || len(data) < cacheFileHeaderLen+f.aead.NonceSize()+f.aead.Overhead() {
+		return nil, false, time.Time{}, false
 	}
 
-	iv := cipherText[:aes.BlockSize]
-	cipherText = cipherText[aes.BlockSize:]
+	header := data[:cacheFileHeaderLen]
+	nonce := data[cacheFileHeaderLen : cacheFileHeaderLen+f.aead.NonceSize()]
+	sealed := data[cacheFileHeaderLen+f.aead.NonceSize():]
+	ad := f.additionalData(header, filename)
+
+	switch mode {
+	case cacheFileModeEncrypted:
+		value, err := f.aead.Open(nil, nonce, sealed, ad)
+		if err != nil {
+			return nil, false, time.Time{}, false
+		}
+		return value, true, saveTime, true
+	default: // cacheFileModePlain
+		tag, value := sealed[:f.aead.Overhead()], sealed[f.aead.Overhead():]
+		if _, err := f.aead.Open(nil, nonce, tag, append(ad, value...)); err != nil {
+			return nil, false, time.Time{}, false
+		}
+		return value, false, saveTime, true
+	}
+}
 
-	stream := cipher.NewCFBDecrypter(f.cipherBlock, iv)
-	stream.XORKeyStream(cipherText, cipherText)
+// additionalData binds the header and the cache file name, so that a file cannot be moved to
+// another key or namespace, or changed to another mode.
+func (f *fileCache) additionalData(header []byte, filename string) []byte {
+	ad := make([]byte, 0, len(header)+len(f.filenamePrefix)+len(filename))
+	ad = append(ad, header...)
+	ad = append(ad, f.filenamePrefix...)
+	return append(ad, filename...)
+}
 
-	return cipherText, nil
+func cacheFileHeader(mode byte, saveTime time.Time) []byte {
+	header := append([]byte(cacheFileMagic), cacheFileVersion, mode)
+	return binary.BigEndian.AppendUint64(header, uint64(saveTime.UnixNano()))
 }
 
-func (f *fileCache) encryptCache(plainText []byte) ([]byte, error) {
-	cipherText := make([]byte, aes.BlockSize+len(plainText))
-	iv := cipherText[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		return nil, cacheProcessingError("failed to create initialization vector", err)
+// parseCacheFileHeader returns the mode and the save time in the header of the cache file data.
+// The header is not authenticated by this function. If the data does not start with the header of
+// the current version, it returns false.
+func parseCacheFileHeader(data []byte) (byte, time.Time, bool) {
+	if len(data) < cacheFileHeaderLen || !bytes.HasPrefix(data, []byte(cacheFileMagic)) || data[len(cacheFileMagic)] != cacheFileVersion {
+		return 0, time.Time{}, false
 	}
 
-	stream := cipher.NewCFBEncrypter(f.cipherBlock, iv)
-	stream.XORKeyStream(cipherText[aes.BlockSize:], plainText)
+	mode := data[len(cacheFileMagic)+1]
+	if mode != cacheFileModePlain && mode != cacheFileModeEncrypted {
+		return 0, time.Time{}, false
+	}
+	saveTime := time.Unix(0, int64(binary.BigEndian.Uint64(data[len(cacheFileMagic)+2:cacheFileHeaderLen])))
 
-	return cipherText, nil
+	return mode, saveTime, true
 }
 
-func (f fileCache) getFileInfo(filename string, hidden bool) (os.FileInfo, error) {
+func (f *fileCache) getFileInfo(filename string, hidden bool) (os.FileInfo, error) {
 	filename = f.filenamePrefix + filename
 	if hidden {
 		filename = "." + filename
@@ -219,7 +453,7 @@ func (f fileCache) getFileInfo(filename string, hidden bool) (os.FileInfo, error
 	return os.Stat((filepath.Join(f.cachePath, filename)))
 }
 
-func (f fileCache) readFile(filename string, hidden bool) ([]byte, error) {
+func (f *fileCache) readFile(filename string, hidden bool) ([]byte, error) {
 	filename = f.filenamePrefix + filename
 	if hidden {
 		filename = "." + filename
@@ -229,26 +463,76 @@ func (f fileCache) readFile(filename string, hidden bool) ([]byte, error) {
 	if err != nil {
 		return nil, cacheProcessingError("failed to read file", err)
 	}
-	data = data[:len(data)-1]
 
-	return data, nil
+	return trimFileEnd(data)
 }
 
-func (f fileCache) writeToFile(filename string, data []byte, hidden bool) error {
+// readSecureFile reads the file like readFile after checking that it is not accessible by others.
+// The opened file is checked, so that it cannot be replaced between the check and the read.
+func (f *fileCache) readSecureFile(filename string, hidden bool) ([]byte, error) {
 	filename = f.filenamePrefix + filename
 	if hidden {
 		filename = "." + filename
 	}
+	path := filepath.Join(f.cachePath, filename)
 
-	file, err := os.Create(filepath.Join(f.cachePath, filename))
+	file, err := os.Open(path)
 	if err != nil {
-		return cacheProcessingError("failed to create file", err)
+		return nil, cacheProcessingError("failed to open file", err)
 	}
 	defer file.Close()
 
+	info, err := file.Stat()
+	if err != nil {
+		return nil, cacheProcessingError("failed to get file info", err)
+	}
+	if err := checkFileSecurity(path, info); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, cacheProcessingError("failed to read file", err)
+	}
+
+	return trimFileEnd(data)
+}
+
+// trimFileEnd removes the newline which writeToFile writes at the end of the file.
+func trimFileEnd(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[len(data)-1] != '\n' {
+		return nil, cacheProcessingError("failed to read file", errTruncatedFile)
+	}
+
+	return data[:len(data)-1], nil
+}
+
+// writeToFile writes data to the file atomically. The data is written to a temporary file, which
+// is readable only by the current user, and the temporary file is renamed to the file.
+func (f *fileCache) writeToFile(filename string, data []byte, hidden bool) error {
+	filename = f.filenamePrefix + filename
+	if hidden {
+		filename = "." + filename
+	}
+
+	file, err := os.CreateTemp(f.cachePath, ".tmp-*")
+	if err != nil {
+		return cacheProcessingError("failed to create file", err)
+	}
+	tmpName := file.Name()
+	defer os.Remove(tmpName) // no-op after the rename
+
 	data = append(data, '\n')
 	if _, err := file.Write(data); err != nil {
-		return err
+		file.Close()
+		return cacheProcessingError("failed to write file", err)
+	}
+	if err := file.Close(); err != nil {
+		return cacheProcessingError("failed to write file", err)
+	}
+
+	if err := os.Rename(tmpName, filepath.Join(f.cachePath, filename)); err != nil {
+		return cacheProcessingError("failed to rename file", err)
 	}
 
 	return nil
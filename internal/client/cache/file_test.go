@@ -17,11 +17,15 @@
 package cache
 
 import (
+	"bytes"
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
+	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -30,59 +34,150 @@ import (
 
 func Test_newFileCache(t *testing.T) {
 	type args struct {
-		cfg            config.FileCacheConfig
 		expireDuration time.Duration
 		options        []Option
 	}
 	tests := []struct {
 		name      string
 		args      args
+		prepare   func(t *testing.T, cachePath string)
 		wantFiles []string
 		wantErr   bool
 	}{
 		{
-			name: "ok",
-			args: args{
-				cfg: config.FileCacheConfig{
-					CachePath: "testdata/constructor",
-				},
-			},
-			wantFiles: []string{"testdata/constructor/_key", "testdata/constructor/._keyHash"},
+			name:      "ok",
+			args:      args{},
+			wantFiles: []string{"_key", "._keyHash"},
 		},
 		{
 			name: "ok with cache keys option",
 			args: args{
-				cfg: config.FileCacheConfig{
-					CachePath: "testdata/constructor",
-				},
 				options: []Option{WithCacheKeys("key1")},
 			},
-			wantFiles: []string{"testdata/constructor/6b657931_key", "testdata/constructor/.6b657931_keyHash"},
+			wantFiles: []string{"6b657931_key", ".6b657931_keyHash"},
+		},
+		{
+			name: "ok: existing key",
+			prepare: func(t *testing.T, cachePath string) {
+				if _, err := newFileCache(config.FileCacheConfig{CachePath: cachePath}, 0); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantFiles: []string{"_key", "._keyHash"},
+		},
+		{
+			name: "error: directory is accessible by others",
+			prepare: func(t *testing.T, cachePath string) {
+				if err := os.Chmod(cachePath, 0755); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: key file is accessible by others",
+			prepare: func(t *testing.T, cachePath string) {
+				if _, err := newFileCache(config.FileCacheConfig{CachePath: cachePath}, 0); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(filepath.Join(cachePath, "_key"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: key hash file is accessible by others",
+			prepare: func(t *testing.T, cachePath string) {
+				if _, err := newFileCache(config.FileCacheConfig{CachePath: cachePath}, 0); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(filepath.Join(cachePath, "._keyHash"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := newFileCache(tt.args.cfg, tt.args.expireDuration, tt.args.options...)
+			cachePath := filepath.Join(t.TempDir(), "cache")
+			if tt.prepare != nil {
+				if err := os.Mkdir(cachePath, 0700); err != nil {
+					t.Fatal(err)
+				}
+				tt.prepare(t, cachePath)
+			}
+
+			_, err := newFileCache(config.FileCacheConfig{CachePath: cachePath}, tt.args.expireDuration, tt.args.options...)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("newFileCache() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			for _, file := range tt.wantFiles {
-				if _, err := os.Stat(file); err != nil {
+				info, err := os.Stat(filepath.Join(cachePath, file))
+				if err != nil {
 					t.Errorf("file is not found = %v", file)
+					continue
+				}
+				if perm := info.Mode().Perm(); perm != 0600 {
+					t.Errorf("permission of %v = %04o, want 0600", file, perm)
 				}
 			}
 		})
 	}
 }
 
+func Test_fileCache_writeToFile(t *testing.T) {
+	f := &fileCache{cachePath: t.TempDir()}
+
+	for _, data := range []string{"old", "new"} {
+		if err := f.writeToFile("file", []byte(data), false); err != nil {
+			t.Fatalf("fileCache.writeToFile() error = %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(f.cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "file" {
+		t.Errorf("files in the cache directory = %v, want only the written file", entries)
+	}
+	b, _ := os.ReadFile(filepath.Join(f.cachePath, "file"))
+	if got, want := string(b), "new\n"; got != want {
+		t.Errorf("fileCache.writeToFile() wrote %q, want %q", got, want)
+	}
+}
+
+func Test_newFileCache_independentOptions(t *testing.T) {
+	cfg := config.FileCacheConfig{
+		CachePath: filepath.Join(t.TempDir(), "cache"),
+	}
+	c1, err := newFileCache(cfg, time.Minute, WithCacheKeys("key1"))
+	if err != nil {
+		t.Fatalf("newFileCache() error = %v", err)
+	}
+	c2, err := newFileCache(cfg, time.Minute)
+	if err != nil {
+		t.Fatalf("newFileCache() error = %v", err)
+	}
+
+	if got, want := c1.(*fileCache).filenamePrefix, keyToHex("key1")+"_"; got != want {
+		t.Errorf("fileCache.filenamePrefix = %v, want %v", got, want)
+	}
+	if got, want := c2.(*fileCache).filenamePrefix, "_"; got != want {
+		t.Errorf("fileCache.filenamePrefix = %v, want %v", got, want)
+	}
+}
+
 func Test_fileCache_SaveAndLoad(t *testing.T) {
-	const cachePath = "testdata/save-and-load"
-	block, _ := aes.NewCipher([]byte("0123456789abcdef0123456789abcdef"))
+	cachePath := t.TempDir()
+	aead := newTestAEAD(t)
 
 	type fields struct {
 		cachePath      string
-		cipherBlock    cipher.Block
+		aead           cipher.AEAD
 		expireDuration time.Duration
 		filenamePrefix string
 	}
@@ -103,7 +198,7 @@ func Test_fileCache_SaveAndLoad(t *testing.T) {
 			name: "ok: save and load plain value",
 			fields: fields{
 				cachePath:      cachePath,
-				cipherBlock:    block,
+				aead:           aead,
 				expireDuration: notExpireDuration,
 			},
 			args: args{
@@ -115,7 +210,7 @@ func Test_fileCache_SaveAndLoad(t *testing.T) {
 			name: "ok: save and load encrypted value",
 			fields: fields{
 				cachePath:      cachePath,
-				cipherBlock:    block,
+				aead:           aead,
 				expireDuration: notExpireDuration,
 			},
 			args: args{
@@ -128,7 +223,7 @@ func Test_fileCache_SaveAndLoad(t *testing.T) {
 			name: "ok: load expired value",
 			fields: fields{
 				cachePath:      cachePath,
-				cipherBlock:    block,
+				aead:           aead,
 				expireDuration: 0,
 			},
 			args: args{
@@ -141,7 +236,7 @@ func Test_fileCache_SaveAndLoad(t *testing.T) {
 			name: "ok: with prefix",
 			fields: fields{
 				cachePath:      cachePath,
-				cipherBlock:    block,
+				aead:           aead,
 				expireDuration: notExpireDuration,
 				filenamePrefix: "test_",
 			},
@@ -155,7 +250,7 @@ func Test_fileCache_SaveAndLoad(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			f := &fileCache{
 				cachePath:      tt.fields.cachePath,
-				cipherBlock:    tt.fields.cipherBlock,
+				aead:           tt.fields.aead,
 				expireDuration: tt.fields.expireDuration,
 				filenamePrefix: tt.fields.filenamePrefix,
 			}
@@ -179,3 +274,299 @@ func Test_fileCache_SaveAndLoad(t *testing.T) {
 		})
 	}
 }
+
+func Test_fileCache_concurrent(t *testing.T) {
+	f := &fileCache{
+		cachePath:      t.TempDir(),
+		aead:           newTestAEAD(t),
+		expireDuration: notExpireDuration,
+	}
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := fmt.Sprintf("key-%d", i%5)
+			if err := f.Save(context.Background(), key, stringPtr(key), true); err != nil {
+				t.Errorf("fileCache.Save() error = %v", err)
+			}
+			got, _, err := f.Load(context.Background(), key, true)
+			if err != nil {
+				t.Errorf("fileCache.Load() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, stringPtr(key)) {
+				t.Errorf("fileCache.Load() got = %v, want %v", got, key)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func Test_fileCache_Load_invalidFile(t *testing.T) {
+	aead := newTestAEAD(t)
+
+	tests := []struct {
+		name    string
+		decrypt bool
+		// modify modifies the cache file of "key" saved by encrypt = true.
+		modify func(t *testing.T, f *fileCache, path string)
+	}{
+		{
+			name:    "tampered encrypted value",
+			decrypt: true,
+			modify: func(t *testing.T, f *fileCache, path string) {
+				b, _ := os.ReadFile(path)
+				b[len(b)-2] ^= 0xff
+				writeTestFile(t, path, b)
+			},
+		},
+		{
+			name: "tampered plain value",
+			modify: func(t *testing.T, f *fileCache, path string) {
+				if err := f.Save(context.Background(), "key", stringPtr("value"), false); err != nil {
+					t.Fatal(err)
+				}
+				b, _ := os.ReadFile(path)
+				b[len(b)-2] = 'X'
+				writeTestFile(t, path, b)
+			},
+		},
+		{
+			name:    "plain value is loaded as sensitive value",
+			decrypt: true,
+			modify: func(t *testing.T, f *fileCache, path string) {
+				if err := f.Save(context.Background(), "key", stringPtr("value"), false); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name:    "moved from another key",
+			decrypt: true,
+			modify: func(t *testing.T, f *fileCache, path string) {
+				if err := f.Save(context.Background(), "other", stringPtr("other"), true); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Rename(filepath.Join(f.cachePath, keyToHex("other")), path); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name:    "empty file",
+			decrypt: true,
+			modify: func(t *testing.T, f *fileCache, path string) {
+				writeTestFile(t, path, nil)
+			},
+		},
+		{
+			name:    "truncated file",
+			decrypt: true,
+			modify: func(t *testing.T, f *fileCache, path string) {
+				b, _ := os.ReadFile(path)
+				writeTestFile(t, path, b[:len(b)/2])
+			},
+		},
+		{
+			name:    "tampered save time",
+			decrypt: true,
+			modify: func(t *testing.T, f *fileCache, path string) {
+				b, _ := os.ReadFile(path)
+				b[cacheFileHeaderLen-1] ^= 0xff
+				writeTestFile(t, path, b)
+			},
+		},
+		{
+			name:    "old version",
+			decrypt: true,
+			modify: func(t *testing.T, f *fileCache, path string) {
+				b, _ := os.ReadFile(path)
+				b[len(cacheFileMagic)] = 1
+				writeTestFile(t, path, b)
+			},
+		},
+		{
+			name:    "old format",
+			decrypt: true,
+			modify: func(t *testing.T, f *fileCache, path string) {
+				// AES-CFB encrypted value of the old format
+				writeTestFile(t, path, append(make([]byte, 16), "value\n"...))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fileCache{
+				cachePath:      t.TempDir(),
+				aead:           aead,
+				expireDuration: notExpireDuration,
+			}
+			if err := f.Save(context.Background(), "key", stringPtr("value"), true); err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(t, f, filepath.Join(f.cachePath, keyToHex("key")))
+
+			gotValue, _, err := f.Load(context.Background(), "key", tt.decrypt)
+			if err != nil {
+				t.Errorf("fileCache.Load() error = %v", err)
+				return
+			}
+			if gotValue != nil {
+				t.Errorf("fileCache.Load() got = %v, want nil", *gotValue)
+			}
+
+			// The file is overwritten by the next save.
+			if err := f.Save(context.Background(), "key", stringPtr("new-value"), true); err != nil {
+				t.Fatal(err)
+			}
+			if gotValue, _, _ := f.Load(context.Background(), "key", true); !reflect.DeepEqual(gotValue, stringPtr("new-value")) {
+				t.Errorf("fileCache.Load() got = %v, want %v", gotValue, "new-value")
+			}
+		})
+	}
+}
+
+func Test_fileCache_Save_encrypted(t *testing.T) {
+	f := &fileCache{
+		cachePath:      t.TempDir(),
+		aead:           newTestAEAD(t),
+		expireDuration: notExpireDuration,
+	}
+	if err := f.Save(context.Background(), "key", stringPtr("secret-value"), true); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(f.cachePath, keyToHex("key")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(b, []byte{'Y', 'S', 'R', 'C', cacheFileVersion, cacheFileModeEncrypted}) {
+		t.Errorf("cache file does not start with the header: %x", b)
+	}
+	if bytes.Contains(b, []byte("secret-value")) {
+		t.Errorf("cache file contains the plain value")
+	}
+}
+
+func Test_fileCache_LoadStale(t *testing.T) {
+	tests := []struct {
+		name        string
+		age         time.Duration
+		staleMaxAge time.Duration
+		decrypt     bool
+		want        *string
+	}{
+		{
+			name:        "ok: not expired",
+			age:         0,
+			staleMaxAge: time.Hour,
+			want:        stringPtr("value"),
+		},
+		{
+			name:        "ok: expired within max age",
+			age:         90 * time.Minute,
+			staleMaxAge: time.Hour,
+			want:        stringPtr("value"),
+		},
+		{
+			name:        "ok: expired over max age",
+			age:         3 * time.Hour,
+			staleMaxAge: time.Hour,
+			want:        nil,
+		},
+		{
+			name:        "ok: stale_if_error is disabled",
+			age:         90 * time.Minute,
+			staleMaxAge: 0,
+			want:        nil,
+		},
+		{
+			name:        "ok: plain text value is not loaded as sensitive",
+			age:         90 * time.Minute,
+			staleMaxAge: time.Hour,
+			decrypt:     true,
+			want:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fileCache{
+				cachePath:      t.TempDir(),
+				aead:           newTestAEAD(t),
+				expireDuration: time.Hour,
+				staleMaxAge:    tt.staleMaxAge,
+			}
+			saveTestFile(t, f, "key", "value", false, time.Now().Add(-tt.age))
+
+			got, err := f.LoadStale(context.Background(), "key", tt.decrypt)
+			if err != nil {
+				t.Errorf("fileCache.LoadStale() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fileCache.LoadStale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_fileCache_Load_modTime(t *testing.T) {
+	f := &fileCache{
+		cachePath:      t.TempDir(),
+		aead:           newTestAEAD(t),
+		expireDuration: time.Hour,
+	}
+	saveTestFile(t, f, "key", "value", true, time.Now().Add(-2*time.Hour))
+
+	// The modification time of the file does not extend the expiration.
+	now := time.Now()
+	if err := os.Chtimes(filepath.Join(f.cachePath, keyToHex("key")), now, now); err != nil {
+		t.Fatal(err)
+	}
+
+	_, expired, err := f.Load(context.Background(), "key", true)
+	if err != nil {
+		t.Errorf("fileCache.Load() error = %v", err)
+		return
+	}
+	if !expired {
+		t.Errorf("fileCache.Load() expired = %v, want true", expired)
+	}
+}
+
+// saveTestFile saves the value as if it was saved at saveTime.
+func saveTestFile(t *testing.T, f *fileCache, key, value string, encrypt bool, saveTime time.Time) {
+	t.Helper()
+
+	data, err := f.sealCache(keyToHex(key), []byte(value), encrypt, saveTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.writeToFile(keyToHex(key), data, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestAEAD(t *testing.T) cipher.AEAD {
+	t.Helper()
+
+	block, err := aes.NewCipher([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aead
+}
+
+func writeTestFile(t *testing.T, path string, b []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
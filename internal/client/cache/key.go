@@ -0,0 +1,262 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmsTypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/dwango/yashiro/pkg/config"
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	keySize             = 32 // AES-256
+	keySaltFileName     = "keySalt"
+	kmsDataKeyFileName  = "kmsDataKey"
+	passphraseSaltSize  = 16
+	passphraseArgonTime = 1
+	passphraseArgonMem  = 64 * 1024
+	passphraseArgonThr  = 4
+)
+
+// keySource provides the key to encrypt cache files.
+type keySource interface {
+	// key returns the key. If the key does not exist, a new key is created.
+	key(ctx context.Context) ([]byte, error)
+	// newKey creates a new key, which replaces the existing key.
+	newKey(ctx context.Context) ([]byte, error)
+}
+
+func newKeySource(cfg config.FileCacheKeyConfig, f *fileCache) (keySource, error) {
+	switch cfg.Source {
+	case config.FileCacheKeySourceUnspecified, config.FileCacheKeySourceFile:
+		return fileKeySource{f: f}, nil
+	case config.FileCacheKeySourceKeyring:
+		service := cfg.KeyringService
+		if len(service) == 0 {
+			service = config.DefaultFileCacheKeyringService
+		}
+		// The key is stored per cache directory and cache keys.
+		user, err := filepath.Abs(filepath.Join(f.cachePath, f.filenamePrefix+keyFileName))
+		if err != nil {
+			return nil, cacheProcessingError("failed to get key name", err)
+		}
+		return keyringKeySource{service: service, user: user}, nil
+	case config.FileCacheKeySourcePassphrase:
+		env := cfg.PassphraseEnv
+		if len(env) == 0 {
+			env = config.DefaultFileCachePassphraseEnv
+		}
+		return passphraseKeySource{f: f, env: env}, nil
+	case config.FileCacheKeySourceAwsKms:
+		if len(cfg.AwsKms.KeyID) == 0 {
+			return nil, fmt.Errorf("%w: key_id of aws_kms is required", ErrInvalidKeySource)
+		}
+		client, err := newKmsClient(cfg.AwsKms)
+		if err != nil {
+			return nil, err
+		}
+		return kmsKeySource{f: f, client: client, keyID: cfg.AwsKms.KeyID}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKeySource, cfg.Source)
+	}
+}
+
+// fileKeySource is the key file in the cache directory.
+type fileKeySource struct {
+	f *fileCache
+}
+
+func (s fileKeySource) key(_ context.Context) ([]byte, error) {
+	return s.f.readOrCreateKey()
+}
+
+func (s fileKeySource) newKey(_ context.Context) ([]byte, error) {
+	return s.f.createKey()
+}
+
+// keyringKeySource is the key stored in the OS keyring, e.g. Secret Service on Linux, Keychain on
+// macOS and Credential Manager on Windows.
+type keyringKeySource struct {
+	service string
+	user    string
+}
+
+func (s keyringKeySource) key(ctx context.Context) ([]byte, error) {
+	encoded, err := keyring.Get(s.service, s.user)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != keySize {
+			return nil, cacheProcessingError("key in the keyring is invalid", err)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, keyring.ErrNotFound) {
+		return nil, cacheProcessingError("failed to get key from the keyring", err)
+	}
+
+	return s.newKey(ctx)
+}
+
+func (s keyringKeySource) newKey(_ context.Context) ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, cacheProcessingError("failed to create key", err)
+	}
+	if err := keyring.Set(s.service, s.user, base64.StdEncoding.EncodeToString(key)); err != nil {
+		return nil, cacheProcessingError("failed to save key to the keyring", err)
+	}
+
+	return key, nil
+}
+
+// passphraseKeySource derives the key from a passphrase in the environment variable by Argon2id.
+// The salt is stored in the cache directory.
+type passphraseKeySource struct {
+	f   *fileCache
+	env string
+}
+
+func (s passphraseKeySource) key(_ context.Context) ([]byte, error) {
+	passphrase, err := s.passphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	salt, err := s.f.readOrCreateFile(keySaltFileName, newSalt)
+	if err != nil {
+		return nil, err
+	}
+
+	return deriveKey(passphrase, salt), nil
+}
+
+// newKey creates a new salt, so that the same passphrase derives a new key.
+func (s passphraseKeySource) newKey(_ context.Context) ([]byte, error) {
+	passphrase, err := s.passphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	salt, err := newSalt()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.f.writeToFile(keySaltFileName, salt, false); err != nil {
+		return nil, err
+	}
+
+	return deriveKey(passphrase, salt), nil
+}
+
+func (s passphraseKeySource) passphrase() (string, error) {
+	passphrase := os.Getenv(s.env)
+	if len(passphrase) == 0 {
+		return "", fmt.Errorf("%w: environment variable '%s' is empty", ErrInvalidKeySource, s.env)
+	}
+
+	return passphrase, nil
+}
+
+func newSalt() ([]byte, error) {
+	salt := make([]byte, passphraseSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, cacheProcessingError("failed to create salt", err)
+	}
+
+	return salt, nil
+}
+
+func deriveKey(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, passphraseArgonTime, passphraseArgonMem, passphraseArgonThr, keySize)
+}
+
+type kmsClient interface {
+	GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+func newKmsClient(cfg config.FileCacheAwsKmsConfig) (kmsClient, error) {
+	var opts []func(*awsConfig.LoadOptions) error
+	if len(cfg.Region) != 0 {
+		opts = append(opts, awsConfig.WithRegion(cfg.Region))
+	}
+	sdkConfig, err := awsConfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, cacheProcessingError("failed to load AWS configuration", err)
+	}
+
+	return kms.NewFromConfig(sdkConfig, func(o *kms.Options) {
+		if len(cfg.Endpoint) != 0 {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	}), nil
+}
+
+// kmsKeySource is a data key generated by AWS KMS. The data key wrapped by the KMS key is stored
+// in the cache directory, and unwrapped by KMS when it is used.
+type kmsKeySource struct {
+	f      *fileCache
+	client kmsClient
+	keyID  string
+}
+
+func (s kmsKeySource) key(ctx context.Context) ([]byte, error) {
+	if _, err := s.f.getFileInfo(kmsDataKeyFileName, false); err != nil {
+		return s.newKey(ctx)
+	}
+
+	wrapped, err := s.f.readFile(kmsDataKeyFileName, false)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := s.client.Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob: wrapped,
+		KeyId:          aws.String(s.keyID),
+	})
+	if err != nil {
+		return nil, cacheProcessingError("failed to decrypt data key by KMS", err)
+	}
+
+	return output.Plaintext, nil
+}
+
+func (s kmsKeySource) newKey(ctx context.Context) ([]byte, error) {
+	output, err := s.client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:   aws.String(s.keyID),
+		KeySpec: kmsTypes.DataKeySpecAes256,
+	})
+	if err != nil {
+		return nil, cacheProcessingError("failed to generate data key by KMS", err)
+	}
+	if err := s.f.writeToFile(kmsDataKeyFileName, output.CiphertextBlob, false); err != nil {
+		return nil, err
+	}
+
+	return output.Plaintext, nil
+}
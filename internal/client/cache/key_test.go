@@ -0,0 +1,370 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/dwango/yashiro/pkg/config"
+	"github.com/zalando/go-keyring"
+)
+
+func Test_newKeySource(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.FileCacheKeyConfig
+		want    keySource
+		wantErr error
+	}{
+		{
+			name: "ok: unspecified",
+			cfg:  config.FileCacheKeyConfig{},
+			want: fileKeySource{},
+		},
+		{
+			name: "ok: file",
+			cfg:  config.FileCacheKeyConfig{Source: config.FileCacheKeySourceFile},
+			want: fileKeySource{},
+		},
+		{
+			name: "ok: keyring",
+			cfg:  config.FileCacheKeyConfig{Source: config.FileCacheKeySourceKeyring},
+			want: keyringKeySource{},
+		},
+		{
+			name: "ok: passphrase",
+			cfg:  config.FileCacheKeyConfig{Source: config.FileCacheKeySourcePassphrase},
+			want: passphraseKeySource{},
+		},
+		{
+			name: "ok: aws kms",
+			cfg: config.FileCacheKeyConfig{
+				Source: config.FileCacheKeySourceAwsKms,
+				AwsKms: config.FileCacheAwsKmsConfig{KeyID: "alias/yashiro", Region: "ap-northeast-1"},
+			},
+			want: kmsKeySource{},
+		},
+		{
+			name:    "error: aws kms without key id",
+			cfg:     config.FileCacheKeyConfig{Source: config.FileCacheKeySourceAwsKms},
+			wantErr: ErrInvalidKeySource,
+		},
+		{
+			name:    "error: unknown source",
+			cfg:     config.FileCacheKeyConfig{Source: "unknown"},
+			wantErr: ErrInvalidKeySource,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newKeySource(tt.cfg, &fileCache{cachePath: t.TempDir()})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("newKeySource() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			var ok bool
+			switch tt.want.(type) {
+			case fileKeySource:
+				_, ok = got.(fileKeySource)
+			case keyringKeySource:
+				_, ok = got.(keyringKeySource)
+			case passphraseKeySource:
+				_, ok = got.(passphraseKeySource)
+			case kmsKeySource:
+				_, ok = got.(kmsKeySource)
+			}
+			if !ok {
+				t.Errorf("newKeySource() = %T, want %T", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_keyringKeySource_key(t *testing.T) {
+	keyring.MockInit()
+
+	s := keyringKeySource{service: config.DefaultFileCacheKeyringService, user: "/tmp/cache/_key"}
+	key, err := s.key(context.Background())
+	if err != nil {
+		t.Fatalf("keyringKeySource.key() error = %v", err)
+	}
+	if len(key) != keySize {
+		t.Errorf("keyringKeySource.key() length = %d, want %d", len(key), keySize)
+	}
+
+	// the same key is returned from the keyring
+	got, err := s.key(context.Background())
+	if err != nil {
+		t.Fatalf("keyringKeySource.key() error = %v", err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Errorf("keyringKeySource.key() = %x, want %x", got, key)
+	}
+
+	// another cache directory has another key
+	other, err := keyringKeySource{service: config.DefaultFileCacheKeyringService, user: "/tmp/other/_key"}.key(context.Background())
+	if err != nil {
+		t.Fatalf("keyringKeySource.key() error = %v", err)
+	}
+	if bytes.Equal(other, key) {
+		t.Errorf("keyringKeySource.key() returns the same key for another user")
+	}
+
+	// invalid key in the keyring
+	if err := keyring.Set(s.service, "invalid", "not base64"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (keyringKeySource{service: s.service, user: "invalid"}).key(context.Background()); !errors.Is(err, ErrCacheProcessing) {
+		t.Errorf("keyringKeySource.key() error = %v, wantErr %v", err, ErrCacheProcessing)
+	}
+}
+
+func Test_passphraseKeySource_key(t *testing.T) {
+	const env = "YASHIRO_TEST_CACHE_PASSPHRASE"
+	f := &fileCache{cachePath: t.TempDir(), filenamePrefix: "_"}
+	s := passphraseKeySource{f: f, env: env}
+
+	t.Setenv(env, "")
+	if _, err := s.key(context.Background()); !errors.Is(err, ErrInvalidKeySource) {
+		t.Errorf("passphraseKeySource.key() error = %v, wantErr %v", err, ErrInvalidKeySource)
+	}
+
+	t.Setenv(env, "passphrase")
+	key, err := s.key(context.Background())
+	if err != nil {
+		t.Fatalf("passphraseKeySource.key() error = %v", err)
+	}
+	if len(key) != keySize {
+		t.Errorf("passphraseKeySource.key() length = %d, want %d", len(key), keySize)
+	}
+	if _, err := f.getFileInfo(keySaltFileName, false); err != nil {
+		t.Errorf("salt file is not created: %v", err)
+	}
+
+	// the same passphrase derives the same key
+	got, err := s.key(context.Background())
+	if err != nil {
+		t.Fatalf("passphraseKeySource.key() error = %v", err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Errorf("passphraseKeySource.key() = %x, want %x", got, key)
+	}
+
+	// a new salt derives another key from the same passphrase
+	rotated, err := s.newKey(context.Background())
+	if err != nil {
+		t.Fatalf("passphraseKeySource.newKey() error = %v", err)
+	}
+	if bytes.Equal(rotated, key) {
+		t.Errorf("passphraseKeySource.newKey() returns the same key")
+	}
+	if got, err := s.key(context.Background()); err != nil || !bytes.Equal(got, rotated) {
+		t.Errorf("passphraseKeySource.key() = %x, %v, want %x", got, err, rotated)
+	}
+	key = rotated
+
+	// another passphrase derives another key
+	t.Setenv(env, "another passphrase")
+	got, err = s.key(context.Background())
+	if err != nil {
+		t.Fatalf("passphraseKeySource.key() error = %v", err)
+	}
+	if bytes.Equal(got, key) {
+		t.Errorf("passphraseKeySource.key() returns the same key for another passphrase")
+	}
+}
+
+func Test_kmsKeySource_key(t *testing.T) {
+	client := newFakeKmsClient(t, "alias/yashiro")
+	f := &fileCache{cachePath: t.TempDir(), filenamePrefix: "_"}
+	s := kmsKeySource{f: f, client: client, keyID: "alias/yashiro"}
+
+	key, err := s.key(context.Background())
+	if err != nil {
+		t.Fatalf("kmsKeySource.key() error = %v", err)
+	}
+	if len(key) != keySize {
+		t.Errorf("kmsKeySource.key() length = %d, want %d", len(key), keySize)
+	}
+	if client.generated != 1 || client.decrypted != 0 {
+		t.Errorf("kmsKeySource.key() generated = %d, decrypted = %d, want 1, 0", client.generated, client.decrypted)
+	}
+
+	// the plaintext key is never written to the cache directory
+	wrapped, err := f.readFile(kmsDataKeyFileName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(wrapped, key) {
+		t.Errorf("data key file contains the plaintext key")
+	}
+
+	// the wrapped key is decrypted by KMS
+	got, err := s.key(context.Background())
+	if err != nil {
+		t.Fatalf("kmsKeySource.key() error = %v", err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Errorf("kmsKeySource.key() = %x, want %x", got, key)
+	}
+	if client.generated != 1 || client.decrypted != 1 {
+		t.Errorf("kmsKeySource.key() generated = %d, decrypted = %d, want 1, 1", client.generated, client.decrypted)
+	}
+
+	// a new data key replaces the wrapped key
+	rotated, err := s.newKey(context.Background())
+	if err != nil {
+		t.Fatalf("kmsKeySource.newKey() error = %v", err)
+	}
+	if bytes.Equal(rotated, key) {
+		t.Errorf("kmsKeySource.newKey() returns the same key")
+	}
+	if got, err := s.key(context.Background()); err != nil || !bytes.Equal(got, rotated) {
+		t.Errorf("kmsKeySource.key() = %x, %v, want %x", got, err, rotated)
+	}
+
+	// another KMS key cannot decrypt the data key
+	s.client = newFakeKmsClient(t, "alias/yashiro")
+	if _, err := s.key(context.Background()); !errors.Is(err, ErrCacheProcessing) {
+		t.Errorf("kmsKeySource.key() error = %v, wantErr %v", err, ErrCacheProcessing)
+	}
+}
+
+func Test_newFileCache_keySource(t *testing.T) {
+	const env = "YASHIRO_TEST_CACHE_PASSPHRASE"
+	t.Setenv(env, "passphrase")
+	cachePath := filepath.Join(t.TempDir(), "cache")
+	cfg := config.FileCacheConfig{
+		CachePath: cachePath,
+		Key: config.FileCacheKeyConfig{
+			Source:        config.FileCacheKeySourcePassphrase,
+			PassphraseEnv: env,
+		},
+	}
+
+	c, err := newFileCache(cfg, notExpireDuration)
+	if err != nil {
+		t.Fatalf("newFileCache() error = %v", err)
+	}
+	if err := c.Save(context.Background(), "key", stringPtr("value"), true); err != nil {
+		t.Fatalf("fileCache.Save() error = %v", err)
+	}
+
+	// the key file is not created
+	if _, err := c.(*fileCache).getFileInfo(keyFileName, false); err == nil {
+		t.Errorf("key file is created with passphrase key source")
+	}
+
+	// the cache is readable with the same passphrase
+	c, err = newFileCache(cfg, notExpireDuration)
+	if err != nil {
+		t.Fatalf("newFileCache() error = %v", err)
+	}
+	got, expired, err := c.Load(context.Background(), "key", true)
+	if err != nil || expired || got == nil || *got != "value" {
+		t.Errorf("fileCache.Load() = %v, %v, %v, want value", got, expired, err)
+	}
+
+	// the cache is not readable with another passphrase
+	t.Setenv(env, "another passphrase")
+	c, err = newFileCache(cfg, notExpireDuration)
+	if err != nil {
+		t.Fatalf("newFileCache() error = %v", err)
+	}
+	got, _, err = c.Load(context.Background(), "key", true)
+	if err != nil || got != nil {
+		t.Errorf("fileCache.Load() = %v, %v, want nil", got, err)
+	}
+}
+
+// fakeKmsClient wraps data keys with a local AES-GCM key instead of a KMS key.
+type fakeKmsClient struct {
+	keyID     string
+	aead      cipher.AEAD
+	generated int
+	decrypted int
+}
+
+func newFakeKmsClient(t *testing.T, keyID string) *fakeKmsClient {
+	t.Helper()
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeKmsClient{keyID: keyID, aead: aead}
+}
+
+func (c *fakeKmsClient) GenerateDataKey(_ context.Context, params *kms.GenerateDataKeyInput, _ ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+	c.generated++
+	if aws.ToString(params.KeyId) != c.keyID {
+		return nil, errors.New("key not found")
+	}
+
+	plaintext := make([]byte, keySize)
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return &kms.GenerateDataKeyOutput{
+		CiphertextBlob: c.aead.Seal(nonce, nonce, plaintext, []byte(c.keyID)),
+		KeyId:          params.KeyId,
+		Plaintext:      plaintext,
+	}, nil
+}
+
+func (c *fakeKmsClient) Decrypt(_ context.Context, params *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	c.decrypted++
+	if aws.ToString(params.KeyId) != c.keyID {
+		return nil, errors.New("key not found")
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(params.CiphertextBlob) < nonceSize {
+		return nil, errors.New("invalid ciphertext")
+	}
+	plaintext, err := c.aead.Open(nil, params.CiphertextBlob[:nonceSize], params.CiphertextBlob[nonceSize:], []byte(c.keyID))
+	if err != nil {
+		return nil, errors.New("invalid ciphertext")
+	}
+
+	return &kms.DecryptOutput{KeyId: params.KeyId, Plaintext: plaintext}, nil
+}
@@ -0,0 +1,242 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cache
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dwango/yashiro/pkg/config"
+)
+
+// FileEntry is a value cached in a file. It does not have the value itself.
+type FileEntry struct {
+	// Namespace is the cache keys of the client joined by "_", e.g. "aws_ap-northeast-1_123456789012".
+	Namespace string
+	Key       string
+	SavedAt   time.Time
+	ExpiresAt time.Time
+	// Sensitive is true if the value is encrypted.
+	Sensitive bool
+
+	filename string
+	valid    bool
+}
+
+// Expired reports whether the entry is expired at t.
+func (e FileEntry) Expired(t time.Time) bool {
+	return t.After(e.ExpiresAt)
+}
+
+// ListFileEntries returns values cached in files of the cache directory, sorted by namespaces and
+// keys. Files written by old versions or broken are not included.
+func ListFileEntries(cfg config.CacheConfig) ([]FileEntry, error) {
+	entries, err := readFileEntries(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(entries, func(e FileEntry) bool {
+		return !e.valid
+	}), nil
+}
+
+// RemoveFileEntries removes files of the entries.
+func RemoveFileEntries(cfg config.CacheConfig, entries []FileEntry) error {
+	cachePath := fileCachePath(cfg.File)
+	for _, e := range entries {
+		if err := os.Remove(filepath.Join(cachePath, e.filename)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return cacheProcessingError("failed to remove file", err)
+		}
+	}
+
+	return nil
+}
+
+// PruneFileEntries removes files of values which are no longer used, i.e. expired and not allowed
+// by stale_if_error policy, or written by old versions or broken. It returns the removed entries.
+func PruneFileEntries(cfg config.CacheConfig) ([]FileEntry, error) {
+	entries, err := readFileEntries(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	expireDuration := cacheExpireDuration(cfg)
+	staleMaxAge := cacheStaleMaxAge(cfg)
+	pruned := slices.DeleteFunc(entries, func(e FileEntry) bool {
+		usable := time.Since(e.SavedAt) <= expireDuration || isStale(e.SavedAt, expireDuration, staleMaxAge)
+		return e.valid && usable
+	})
+	if err := RemoveFileEntries(cfg, pruned); err != nil {
+		return nil, err
+	}
+
+	return pruned, nil
+}
+
+// RotateFileKeys replaces the key of each namespace in the cache directory with a new key, and
+// encrypts cached values again with it. It returns the rotated namespaces. All values are opened
+// before any key is replaced, so that nothing is changed if a value cannot be decrypted, e.g. with
+// a wrong key source.
+func RotateFileKeys(ctx context.Context, cfg config.CacheConfig) ([]string, error) {
+	files, err := readCacheDir(fileCachePath(cfg.File))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string][]string)
+	for _, f := range files {
+		if _, ok := keys[f.namespace]; !ok {
+			keys[f.namespace] = nil
+		}
+		if f.isEntry {
+			keys[f.namespace] = append(keys[f.namespace], f.key)
+		}
+	}
+
+	namespaces := slices.Sorted(maps.Keys(keys))
+	caches := make([]*fileCache, len(namespaces))
+	values := make([][]cachedFileValue, len(namespaces))
+	for i, namespace := range namespaces {
+		c, err := newFileCache(cfg.File, cacheExpireDuration(cfg), WithCacheKeys(namespace))
+		if err != nil {
+			return nil, fmt.Errorf("namespace '%s': %w", namespace, err)
+		}
+		caches[i] = c.(*fileCache)
+		values[i], err = caches[i].openValues(keys[namespace])
+		if err != nil {
+			return nil, fmt.Errorf("namespace '%s': %w", namespace, err)
+		}
+	}
+
+	var rotated []string
+	for i, namespace := range namespaces {
+		if err := caches[i].rotateKey(ctx, values[i]); err != nil {
+			return rotated, fmt.Errorf("namespace '%s': %w", namespace, err)
+		}
+		rotated = append(rotated, namespace)
+	}
+
+	return rotated, nil
+}
+
+func readFileEntries(cfg config.CacheConfig) ([]FileEntry, error) {
+	cachePath := fileCachePath(cfg.File)
+	files, err := readCacheDir(cachePath)
+	if err != nil {
+		return nil, err
+	}
+
+	expireDuration := cacheExpireDuration(cfg)
+	entries := make([]FileEntry, 0, len(files))
+	for _, f := range files {
+		if !f.isEntry {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(cachePath, f.name))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// removed after reading the directory
+				continue
+			}
+			return nil, cacheProcessingError("failed to read file", err)
+		}
+		// The header is read without the key, so it is not authenticated here. Load authenticates
+		// it before using the value.
+		mode, saveTime, ok := parseCacheFileHeader(data)
+
+		entries = append(entries, FileEntry{
+			Namespace: f.namespace,
+			Key:       f.key,
+			SavedAt:   saveTime,
+			ExpiresAt: saveTime.Add(expireDuration),
+			Sensitive: mode == cacheFileModeEncrypted,
+			filename:  f.name,
+			valid:     ok,
+		})
+	}
+
+	return entries, nil
+}
+
+// cacheDirFile is a file in the cache directory, which is named "<namespace>_<key>" or
+// "<namespace>_key" for the key file, where the namespace and the key are hex-encoded.
+type cacheDirFile struct {
+	name      string
+	namespace string
+	key       string // decoded key of a cached value
+	isEntry   bool   // false for files of the key
+}
+
+// readCacheDir returns files of the cache directory sorted by namespaces and keys. Hidden files
+// and files not written by the cache are ignored. If the directory does not exist, it returns nil.
+func readCacheDir(cachePath string) ([]cacheDirFile, error) {
+	dirEntries, err := os.ReadDir(cachePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, cacheProcessingError("failed to read cache directory", err)
+	}
+
+	var files []cacheDirFile
+	for _, d := range dirEntries {
+		name := d.Name()
+		if d.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		namespaceHex, rest, ok := strings.Cut(name, "_")
+		if !ok {
+			continue
+		}
+		namespace, err := hex.DecodeString(namespaceHex)
+		if err != nil {
+			continue
+		}
+
+		f := cacheDirFile{name: name, namespace: string(namespace)}
+		switch rest {
+		case keyFileName, keySaltFileName, kmsDataKeyFileName:
+		default:
+			key, err := hex.DecodeString(rest)
+			if err != nil || len(key) == 0 {
+				continue
+			}
+			f.key = string(key)
+			f.isEntry = true
+		}
+		files = append(files, f)
+	}
+
+	slices.SortFunc(files, func(a, b cacheDirFile) int {
+		if c := strings.Compare(a.namespace, b.namespace); c != 0 {
+			return c
+		}
+		return strings.Compare(a.key, b.key)
+	})
+
+	return files, nil
+}
@@ -0,0 +1,306 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dwango/yashiro/pkg/config"
+)
+
+// newTestFileCaches creates caches of namespaces "aws_ap-northeast-1_123456789012" and "gcp" in a
+// new cache directory, and returns the configuration of them.
+func newTestFileCaches(t *testing.T) (config.CacheConfig, map[string]Cache) {
+	t.Helper()
+
+	cfg := config.CacheConfig{
+		Type:           config.CacheTypeFile,
+		ExpireDuration: config.Duration(time.Hour),
+		File:           config.FileCacheConfig{CachePath: filepath.Join(t.TempDir(), "cache")},
+	}
+	caches := make(map[string]Cache)
+	for _, keys := range [][]string{{"aws", "ap-northeast-1", "123456789012"}, {"gcp"}} {
+		c, err := newFileCache(cfg.File, time.Hour, WithCacheKeys(keys...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		caches[keys[0]] = c
+	}
+
+	return cfg, caches
+}
+
+func saveTestValue(t *testing.T, c Cache, key, value string, encrypt bool, age time.Duration) {
+	t.Helper()
+
+	saveTestFile(t, c.(*fileCache), key, value, encrypt, time.Now().Add(-age))
+}
+
+// entryKeys returns "<namespace>:<key>" of entries.
+func entryKeys(entries []FileEntry) []string {
+	keys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		keys = append(keys, e.Namespace+":"+e.Key)
+	}
+	return keys
+}
+
+func TestListFileEntries(t *testing.T) {
+	cfg, caches := newTestFileCaches(t)
+	saveTestValue(t, caches["aws"], "/app/password", "secret", true, 0)
+	saveTestValue(t, caches["aws"], "/app/host", "localhost", false, 2*time.Hour)
+	saveTestValue(t, caches["gcp"], "projects/p/secrets/s/versions/latest", "secret", true, 0)
+	// a file written by old versions is not listed
+	writeTestFile(t, filepath.Join(cfg.File.CachePath, keyToHex("gcp")+"_"+keyToHex("legacy")), []byte("legacy"))
+
+	got, err := ListFileEntries(cfg)
+	if err != nil {
+		t.Fatalf("ListFileEntries() error = %v", err)
+	}
+
+	wantKeys := []string{
+		"aws_ap-northeast-1_123456789012:/app/host",
+		"aws_ap-northeast-1_123456789012:/app/password",
+		"gcp:projects/p/secrets/s/versions/latest",
+	}
+	if !reflect.DeepEqual(entryKeys(got), wantKeys) {
+		t.Fatalf("ListFileEntries() = %v, want %v", entryKeys(got), wantKeys)
+	}
+
+	now := time.Now()
+	wantSensitive := []bool{false, true, true}
+	wantExpired := []bool{true, false, false}
+	for i, e := range got {
+		if e.Sensitive != wantSensitive[i] {
+			t.Errorf("ListFileEntries()[%d].Sensitive = %v, want %v", i, e.Sensitive, wantSensitive[i])
+		}
+		if e.Expired(now) != wantExpired[i] {
+			t.Errorf("ListFileEntries()[%d].Expired() = %v, want %v", i, e.Expired(now), wantExpired[i])
+		}
+		if !e.ExpiresAt.Equal(e.SavedAt.Add(time.Hour)) {
+			t.Errorf("ListFileEntries()[%d].ExpiresAt = %v, want %v", i, e.ExpiresAt, e.SavedAt.Add(time.Hour))
+		}
+	}
+}
+
+func TestListFileEntries_notExist(t *testing.T) {
+	cfg := config.CacheConfig{File: config.FileCacheConfig{CachePath: filepath.Join(t.TempDir(), "not-exist")}}
+
+	got, err := ListFileEntries(cfg)
+	if err != nil || len(got) != 0 {
+		t.Errorf("ListFileEntries() = %v, %v, want empty", got, err)
+	}
+}
+
+func TestRemoveFileEntries(t *testing.T) {
+	cfg, caches := newTestFileCaches(t)
+	saveTestValue(t, caches["aws"], "/app/password", "secret", true, 0)
+	saveTestValue(t, caches["aws"], "/app/host", "localhost", false, 0)
+
+	entries, err := ListFileEntries(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveFileEntries(cfg, entries[:1]); err != nil {
+		t.Fatalf("RemoveFileEntries() error = %v", err)
+	}
+	// removing again is not an error
+	if err := RemoveFileEntries(cfg, entries[:1]); err != nil {
+		t.Fatalf("RemoveFileEntries() error = %v", err)
+	}
+
+	got, err := ListFileEntries(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"aws_ap-northeast-1_123456789012:/app/password"}; !reflect.DeepEqual(entryKeys(got), want) {
+		t.Errorf("ListFileEntries() = %v, want %v", entryKeys(got), want)
+	}
+
+	// the key is not removed
+	if _, err := caches["aws"].(*fileCache).getFileInfo(keyFileName, false); err != nil {
+		t.Errorf("key file is removed: %v", err)
+	}
+}
+
+func TestPruneFileEntries(t *testing.T) {
+	tests := []struct {
+		name         string
+		staleIfError config.StaleIfErrorConfig
+		wantPruned   []string
+	}{
+		{
+			name: "ok: expired values are removed",
+			wantPruned: []string{
+				"aws_ap-northeast-1_123456789012:/app/expired",
+				"aws_ap-northeast-1_123456789012:/app/too-old",
+				"gcp:legacy",
+			},
+		},
+		{
+			name:         "ok: values allowed by stale_if_error are kept",
+			staleIfError: config.StaleIfErrorConfig{Enabled: true, MaxAge: config.Duration(2 * time.Hour)},
+			wantPruned: []string{
+				"aws_ap-northeast-1_123456789012:/app/too-old",
+				"gcp:legacy",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, caches := newTestFileCaches(t)
+			cfg.StaleIfError = tt.staleIfError
+			saveTestValue(t, caches["aws"], "/app/fresh", "value", false, 0)
+			saveTestValue(t, caches["aws"], "/app/expired", "value", false, 2*time.Hour)
+			saveTestValue(t, caches["aws"], "/app/too-old", "value", false, 4*time.Hour)
+			writeTestFile(t, filepath.Join(cfg.File.CachePath, keyToHex("gcp")+"_"+keyToHex("legacy")), []byte("legacy"))
+
+			got, err := PruneFileEntries(cfg)
+			if err != nil {
+				t.Fatalf("PruneFileEntries() error = %v", err)
+			}
+			if !reflect.DeepEqual(entryKeys(got), tt.wantPruned) {
+				t.Errorf("PruneFileEntries() = %v, want %v", entryKeys(got), tt.wantPruned)
+			}
+
+			for _, e := range got {
+				if _, err := os.Stat(filepath.Join(cfg.File.CachePath, e.filename)); !os.IsNotExist(err) {
+					t.Errorf("file of %s:%s is not removed: %v", e.Namespace, e.Key, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRotateFileKeys(t *testing.T) {
+	cfg, caches := newTestFileCaches(t)
+	saveTestValue(t, caches["aws"], "/app/password", "secret", true, 0)
+	saveTestValue(t, caches["aws"], "/app/host", "localhost", false, 2*time.Hour)
+	saveTestValue(t, caches["gcp"], "secret", "gcp-secret", true, 0)
+
+	aws := caches["aws"].(*fileCache)
+	oldKey, err := aws.readFile(keyFileName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := ListFileEntries(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := RotateFileKeys(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("RotateFileKeys() error = %v", err)
+	}
+	if want := []string{"aws_ap-northeast-1_123456789012", "gcp"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RotateFileKeys() = %v, want %v", got, want)
+	}
+
+	newKey, err := aws.readFile(keyFileName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(newKey, oldKey) {
+		t.Errorf("key is not rotated")
+	}
+
+	// the old key cannot read cached values
+	if value, _, err := aws.Load(context.Background(), "/app/password", true); err != nil || value != nil {
+		t.Errorf("fileCache.Load() with the old key = %v, %v, want nil", value, err)
+	}
+
+	// the new key reads cached values, which expire as before
+	after, err := ListFileEntries(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(after, before) {
+		t.Errorf("ListFileEntries() after rotation = %v, want %v", after, before)
+	}
+	c, err := newFileCache(cfg.File, time.Hour, WithCacheKeys("aws", "ap-northeast-1", "123456789012"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{"/app/password": "secret", "/app/host": "localhost"} {
+		value, _, err := c.Load(context.Background(), key, key == "/app/password")
+		if err != nil || value == nil || *value != want {
+			t.Errorf("fileCache.Load(%s) = %v, %v, want %s", key, value, err, want)
+		}
+	}
+}
+
+func TestRotateFileKeys_undecryptable(t *testing.T) {
+	cfg, caches := newTestFileCaches(t)
+	saveTestValue(t, caches["aws"], "/app/password", "secret", true, 0)
+	saveTestValue(t, caches["gcp"], "secret", "gcp-secret", true, 0)
+
+	aws := caches["aws"].(*fileCache)
+	oldKey, err := aws.readFile(keyFileName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := ListFileEntries(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tamper the value of the last namespace, as if it were encrypted with another key.
+	for _, e := range before {
+		if e.Namespace != "gcp" {
+			continue
+		}
+		path := filepath.Join(cfg.File.CachePath, e.filename)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data[len(data)-2] ^= 0xff
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := RotateFileKeys(context.Background(), cfg)
+	if !errors.Is(err, ErrUndecryptable) {
+		t.Errorf("RotateFileKeys() error = %v, want %v", err, ErrUndecryptable)
+	}
+	if len(got) != 0 {
+		t.Errorf("RotateFileKeys() = %v, want no rotated namespaces", got)
+	}
+
+	// Nothing is removed or rotated.
+	after, err := ListFileEntries(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(after, before) {
+		t.Errorf("ListFileEntries() after rotation = %v, want %v", after, before)
+	}
+	newKey, err := aws.readFile(keyFileName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(newKey, oldKey) {
+		t.Errorf("key is rotated")
+	}
+}
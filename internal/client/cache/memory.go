@@ -19,17 +19,20 @@ package cache
 import (
 	"context"
 	"strings"
+	"sync"
 	"time"
 )
 
 type memoryCache struct {
+	mu             sync.RWMutex
 	caches         map[string]*cacheData
 	expireDuration time.Duration
+	staleMaxAge    time.Duration
 	keyPrefix      string
 }
 
 func newMemoryCache(expireDuration time.Duration, options ...Option) (Cache, error) {
-	opts := defaultOpts
+	opts := defaultOpts()
 	for _, o := range options {
 		o(opts)
 	}
@@ -39,6 +42,7 @@ func newMemoryCache(expireDuration time.Duration, options ...Option) (Cache, err
 	return &memoryCache{
 		caches:         make(map[string]*cacheData),
 		expireDuration: expireDuration,
+		staleMaxAge:    opts.StaleMaxAge,
 		keyPrefix:      keyPrefix,
 	}, nil
 }
@@ -49,8 +53,10 @@ type cacheData struct {
 }
 
 // Load implements Cache.
-func (m memoryCache) Load(_ context.Context, key string, _ bool) (*string, bool, error) {
+func (m *memoryCache) Load(_ context.Context, key string, _ bool) (*string, bool, error) {
+	m.mu.RLock()
 	data, ok := m.caches[m.keyPrefix+key]
+	m.mu.RUnlock()
 	if !ok {
 		return nil, false, nil
 	}
@@ -62,6 +68,18 @@ func (m memoryCache) Load(_ context.Context, key string, _ bool) (*string, bool,
 	return &data.value, false, nil
 }
 
+// LoadStale implements Cache.
+func (m *memoryCache) LoadStale(_ context.Context, key string, _ bool) (*string, error) {
+	m.mu.RLock()
+	data, ok := m.caches[m.keyPrefix+key]
+	m.mu.RUnlock()
+	if !ok || !isStale(data.saveTime, m.expireDuration, m.staleMaxAge) {
+		return nil, nil
+	}
+
+	return &data.value, nil
+}
+
 // Save implements Cache.
 func (m *memoryCache) Save(_ context.Context, key string, value *string, _ bool) error {
 	if value == nil {
@@ -72,7 +90,9 @@ func (m *memoryCache) Save(_ context.Context, key string, value *string, _ bool)
 		value:    *value,
 		saveTime: time.Now(),
 	}
+	m.mu.Lock()
 	m.caches[m.keyPrefix+key] = data
+	m.mu.Unlock()
 
 	return nil
 }
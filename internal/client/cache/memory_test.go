@@ -18,7 +18,9 @@ package cache
 
 import (
 	"context"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
@@ -34,7 +36,29 @@ func Test_newMemoryCache(t *testing.T) {
 		want    Cache
 		wantErr bool
 	}{
-		// TODO: Add test cases.
+		{
+			name: "ok",
+			args: args{
+				expireDuration: time.Minute,
+			},
+			want: &memoryCache{
+				caches:         make(map[string]*cacheData),
+				expireDuration: time.Minute,
+				keyPrefix:      "_",
+			},
+		},
+		{
+			name: "ok with cache keys option",
+			args: args{
+				expireDuration: time.Minute,
+				options:        []Option{WithCacheKeys("key1", "key2")},
+			},
+			want: &memoryCache{
+				caches:         make(map[string]*cacheData),
+				expireDuration: time.Minute,
+				keyPrefix:      keyToHex("key1_key2") + "_",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -50,6 +74,24 @@ func Test_newMemoryCache(t *testing.T) {
 	}
 }
 
+func Test_newMemoryCache_independentOptions(t *testing.T) {
+	c1, err := newMemoryCache(time.Minute, WithCacheKeys("key1"))
+	if err != nil {
+		t.Fatalf("newMemoryCache() error = %v", err)
+	}
+	c2, err := newMemoryCache(time.Minute)
+	if err != nil {
+		t.Fatalf("newMemoryCache() error = %v", err)
+	}
+
+	if got, want := c1.(*memoryCache).keyPrefix, keyToHex("key1")+"_"; got != want {
+		t.Errorf("memoryCache.keyPrefix = %v, want %v", got, want)
+	}
+	if got, want := c2.(*memoryCache).keyPrefix, "_"; got != want {
+		t.Errorf("memoryCache.keyPrefix = %v, want %v", got, want)
+	}
+}
+
 func Test_memoryCache_SaveAndLoad(t *testing.T) {
 	type fields struct {
 		caches         map[string]*cacheData
@@ -132,3 +174,91 @@ func Test_memoryCache_SaveAndLoad(t *testing.T) {
 		})
 	}
 }
+
+func Test_memoryCache_LoadStale(t *testing.T) {
+	tests := []struct {
+		name        string
+		age         time.Duration
+		staleMaxAge time.Duration
+		want        *string
+	}{
+		{
+			name:        "ok: not expired",
+			age:         0,
+			staleMaxAge: time.Hour,
+			want:        stringPtr("value"),
+		},
+		{
+			name:        "ok: expired within max age",
+			age:         90 * time.Minute,
+			staleMaxAge: time.Hour,
+			want:        stringPtr("value"),
+		},
+		{
+			name:        "ok: expired over max age",
+			age:         3 * time.Hour,
+			staleMaxAge: time.Hour,
+			want:        nil,
+		},
+		{
+			name:        "ok: stale_if_error is disabled",
+			age:         90 * time.Minute,
+			staleMaxAge: 0,
+			want:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &memoryCache{
+				caches: map[string]*cacheData{
+					"key": {value: "value", saveTime: time.Now().Add(-tt.age)},
+				},
+				expireDuration: time.Hour,
+				staleMaxAge:    tt.staleMaxAge,
+			}
+
+			got, err := m.LoadStale(context.Background(), "key", false)
+			if err != nil {
+				t.Errorf("memoryCache.LoadStale() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("memoryCache.LoadStale() = %v, want %v", got, tt.want)
+			}
+
+			// not found
+			got, err = m.LoadStale(context.Background(), "not-found", false)
+			if err != nil || got != nil {
+				t.Errorf("memoryCache.LoadStale() = %v, %v, want nil", got, err)
+			}
+		})
+	}
+}
+
+func Test_memoryCache_concurrent(t *testing.T) {
+	m := &memoryCache{
+		caches:         make(map[string]*cacheData),
+		expireDuration: notExpireDuration,
+	}
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := fmt.Sprintf("key-%d", i%5)
+			if err := m.Save(context.Background(), key, stringPtr(key), false); err != nil {
+				t.Errorf("memoryCache.Save() error = %v", err)
+			}
+			got, _, err := m.Load(context.Background(), key, false)
+			if err != nil {
+				t.Errorf("memoryCache.Load() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, stringPtr(key)) {
+				t.Errorf("memoryCache.Load() got = %v, want %v", got, key)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -16,6 +16,8 @@
 
 package cache
 
+import "time"
+
 // Option is configurable Cache behavior.
 type Option func(*opts)
 
@@ -26,10 +28,23 @@ func WithCacheKeys(keys ...string) Option {
 	}
 }
 
+// WithStaleMaxAge sets how long a value can be loaded by LoadStale after it expires. If d is 0,
+// LoadStale always returns nil.
+func WithStaleMaxAge(d time.Duration) Option {
+	return func(o *opts) {
+		o.StaleMaxAge = d
+	}
+}
+
 type opts struct {
-	CacheKeys []string
+	CacheKeys   []string
+	StaleMaxAge time.Duration
 }
 
-var defaultOpts = &opts{
-	CacheKeys: nil,
+// defaultOpts returns new options for each cache.
+func defaultOpts() *opts {
+	return &opts{
+		CacheKeys:   nil,
+		StaleMaxAge: 0,
+	}
 }
@@ -0,0 +1,27 @@
+//go:build !unix
+
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import "os"
+
+// checkFileSecurity does nothing, because permissions and owners of files are not represented by
+// file modes on this platform.
+func checkFileSecurity(_ string, _ os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,40 @@
+//go:build unix
+
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkFileSecurity returns an error if the file or directory is accessible by group or others,
+// or is owned by another user.
+func checkFileSecurity(path string, info os.FileInfo) error {
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Errorf("%w: '%s' is accessible by group or others (%04o), change the mode to %04o",
+			ErrInsecureFile, path, perm, perm&0700)
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%w: '%s' is owned by another user (uid=%d)", ErrInsecureFile, path, stat.Uid)
+	}
+
+	return nil
+}
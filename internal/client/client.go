@@ -20,9 +20,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
 
+	"github.com/dwango/yashiro/internal/client/cache"
 	"github.com/dwango/yashiro/internal/values"
 	"github.com/dwango/yashiro/pkg/config"
+	"github.com/dwango/yashiro/pkg/provider"
 )
 
 // Define errors
@@ -32,29 +36,164 @@ var (
 )
 
 // Client is the external stores client.
-type Client interface {
-	GetValues(ctx context.Context, ignoreNotFound bool) (values.Values, error)
-}
+type Client = provider.Provider
 
-// New returns a new Client.
-func New(cfg *config.Config) (Client, error) {
-	var client Client
-	var err error
+// Lookuper looks up values which are not declared in the configuration at render time. If the
+// value is not found, it returns nil and nil error.
+type Lookuper interface {
+	// LookupParameter gets a parameter from Parameter Store.
+	LookupParameter(ctx context.Context, name string) (*string, error)
+	// LookupSecret gets a secret from Secrets Manager.
+	LookupSecret(ctx context.Context, id string) (*string, error)
+}
 
-	if cfg.Aws != nil {
-		client, err = newAwsClient(cfg)
+// FindLookuper returns c or the first client of c which implements Lookuper.
+func FindLookuper(c Client) (Lookuper, bool) {
+	if l, ok := c.(Lookuper); ok {
+		return l, true
 	}
+	if m, ok := c.(multiClient); ok {
+		for _, c := range m {
+			if l, ok := c.(Lookuper); ok {
+				return l, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// AWS is built in. Other providers are registered by importing their packages, e.g.
+// github.com/dwango/yashiro/pkg/provider/vault.
+func init() {
+	provider.Register("aws", newAwsClient)
+}
+
+// New returns a new Client which gets values from all configured providers.
+func New(cfg *config.Config) (Client, error) {
+	providers, err := provider.New(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	if client == nil {
+	switch len(providers) {
+	case 0:
 		return nil, ErrNotfoundValueConfig
+	case 1:
+		return providers[0], nil
+	default:
+		return multiClient(providers), nil
+	}
+}
+
+// multiClient gets values from multiple clients. Values of a later client overwrite values of
+// an earlier one which have the same reference name.
+type multiClient []Client
+
+func (m multiClient) GetValues(ctx context.Context, ignoreNotFound bool) (values.Values, error) {
+	return m.getValues(func(c Client) (values.Values, error) {
+		return c.GetValues(ctx, ignoreNotFound)
+	})
+}
+
+// GetValuesByKeys gets values of the top-level keys from clients. Clients which cannot select
+// values by keys get all values.
+func (m multiClient) GetValuesByKeys(ctx context.Context, keys []string, ignoreNotFound bool) (values.Values, error) {
+	return m.getValues(func(c Client) (values.Values, error) {
+		return provider.GetValuesByKeys(ctx, c, keys, ignoreNotFound)
+	})
+}
+
+func (m multiClient) getValues(get func(Client) (values.Values, error)) (values.Values, error) {
+	vals := make(values.Values)
+	for _, c := range m {
+		v, err := get(c)
+		if err != nil {
+			return nil, err
+		}
+		for k, val := range v {
+			vals[k] = val
+		}
+	}
+
+	return vals, nil
+}
+
+// SelectValues returns value configs whose reference names are in keys. Configs whose reference
+// names are known only after getting values, for which refName returns false, are always selected.
+func SelectValues[T any](cfgs []T, keys []string, refName func(T) (string, bool)) []T {
+	selected := make([]T, 0, len(cfgs))
+	for _, v := range cfgs {
+		if name, ok := refName(v); !ok || slices.Contains(keys, name) {
+			selected = append(selected, v)
+		}
 	}
 
-	return client, nil
+	return selected
 }
 
-func gettingValueError(name string, err error) error {
+// ReferenceName returns the reference name of a value config.
+func ReferenceName[T config.Value](v T) (string, bool) {
+	return v.GetReferenceName(), true
+}
+
+// GettingValueError wraps err of getting the value of the name with ErrGettingValue.
+func GettingValueError(name string, err error) error {
 	return fmt.Errorf("%w: name='%s': %w", ErrGettingValue, name, err)
 }
+
+// GetWithCache returns a cached value if it exists and is not expired. Otherwise, it gets a value
+// by using fetch and creates or updates the cache. If fetch fails with an error for which
+// isTransient returns true, an expired value is returned as long as stale_if_error policy allows
+// it. If c is nil, fetch is always used.
+func GetWithCache(ctx context.Context, c cache.Cache, key string, sensitive bool, isTransient func(error) bool, fetch func(context.Context) (*string, error)) (*string, error) {
+	if c == nil {
+		return fetch(ctx)
+	}
+
+	// Load from cache.
+	value, expired, err := c.Load(ctx, key, sensitive)
+	if err != nil {
+		return nil, err
+	}
+
+	// If a cache value is expired or not found, get a value from the external store.
+	if value == nil || expired {
+		fetched, err := fetch(ctx)
+		if err != nil {
+			if stale := loadStale(ctx, c, key, sensitive, isTransient, err); stale != nil {
+				warnStale(ctx, key, err)
+				return stale, nil
+			}
+			return nil, err
+		}
+		value = fetched
+
+		// Create or update cache.
+		if err := c.Save(ctx, key, value, sensitive); err != nil {
+			return nil, err
+		}
+	}
+
+	return value, nil
+}
+
+// loadStale returns an expired cached value to be used instead of err, if isTransient reports err
+// is transient and stale_if_error policy allows it. Otherwise, it returns nil.
+func loadStale(ctx context.Context, c cache.Cache, key string, sensitive bool, isTransient func(error) bool, err error) *string {
+	if isTransient == nil || !isTransient(err) {
+		return nil
+	}
+
+	value, loadErr := c.LoadStale(ctx, key, sensitive)
+	if loadErr != nil {
+		return nil
+	}
+
+	return value
+}
+
+// warnStale warns that an expired cached value is used because getting the value failed.
+func warnStale(ctx context.Context, key string, err error) {
+	slog.WarnContext(ctx, "using expired cached value because getting value failed", "key", key, "error", err)
+}
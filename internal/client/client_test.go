@@ -18,9 +18,11 @@ package client
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"testing"
 
+	"github.com/dwango/yashiro/internal/values"
 	"github.com/dwango/yashiro/pkg/config"
 )
 
@@ -56,6 +58,149 @@ func TestNew(t *testing.T) {
 	}
 }
 
+type mockClient func(ctx context.Context, ignoreNotFound bool) (values.Values, error)
+
+func (m mockClient) GetValues(ctx context.Context, ignoreNotFound bool) (values.Values, error) {
+	return m(ctx, ignoreNotFound)
+}
+
+func Test_multiClient_GetValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       multiClient
+		want    values.Values
+		wantErr bool
+	}{
+		{
+			name: "ok: merge values",
+			m: multiClient{
+				mockClient(func(context.Context, bool) (values.Values, error) {
+					return values.Values{"key1": "value1", "key2": "value2"}, nil
+				}),
+				mockClient(func(context.Context, bool) (values.Values, error) {
+					return values.Values{"key2": "overwritten", "key3": "value3"}, nil
+				}),
+			},
+			want: values.Values{"key1": "value1", "key2": "overwritten", "key3": "value3"},
+		},
+		{
+			name: "error: a client returns error",
+			m: multiClient{
+				mockClient(func(context.Context, bool) (values.Values, error) {
+					return values.Values{"key1": "value1"}, nil
+				}),
+				mockClient(func(context.Context, bool) (values.Values, error) {
+					return nil, errors.New("error")
+				}),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.m.GetValues(context.Background(), false)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("multiClient.GetValues() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("multiClient.GetValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type mockSelectiveClient struct {
+	mockClient
+	getValuesByKeys func(ctx context.Context, keys []string, ignoreNotFound bool) (values.Values, error)
+}
+
+func (m mockSelectiveClient) GetValuesByKeys(ctx context.Context, keys []string, ignoreNotFound bool) (values.Values, error) {
+	return m.getValuesByKeys(ctx, keys, ignoreNotFound)
+}
+
+func Test_multiClient_GetValuesByKeys(t *testing.T) {
+	m := multiClient{
+		mockClient(func(context.Context, bool) (values.Values, error) {
+			return values.Values{"key1": "value1", "key2": "value2"}, nil
+		}),
+		mockSelectiveClient{
+			getValuesByKeys: func(_ context.Context, keys []string, _ bool) (values.Values, error) {
+				vals := values.Values{}
+				for _, k := range keys {
+					vals[k] = "selected"
+				}
+				return vals, nil
+			},
+		},
+	}
+
+	got, err := m.GetValuesByKeys(context.Background(), []string{"key2"}, false)
+	if err != nil {
+		t.Fatalf("multiClient.GetValuesByKeys() error = %v", err)
+	}
+	if want := (values.Values{"key1": "value1", "key2": "selected"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("multiClient.GetValuesByKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestFindLookuper(t *testing.T) {
+	awsCli := &awsClient{}
+	tests := []struct {
+		name   string
+		c      Client
+		want   Lookuper
+		wantOk bool
+	}{
+		{
+			name:   "ok: client",
+			c:      awsCli,
+			want:   awsCli,
+			wantOk: true,
+		},
+		{
+			name:   "ok: multi client",
+			c:      multiClient{mockClient(nil), awsCli},
+			want:   awsCli,
+			wantOk: true,
+		},
+		{
+			name:   "not found",
+			c:      multiClient{mockClient(nil), mockClient(nil)},
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOk := FindLookuper(tt.c)
+			if gotOk != tt.wantOk {
+				t.Errorf("FindLookuper() ok = %v, want %v", gotOk, tt.wantOk)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("FindLookuper() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_selectValues(t *testing.T) {
+	t.Run("value configs", func(t *testing.T) {
+		cfgs := []config.AwsParameterStoreValueConfig{
+			{ValueConfig: config.ValueConfig{Name: "key1"}},
+			{ValueConfig: config.ValueConfig{Name: "/name", Ref: stringPtr("key2")}},
+			{ValueConfig: config.ValueConfig{Name: "key3"}},
+			{Path: "/app"},
+			{Path: "/app", ValueConfig: config.ValueConfig{Ref: stringPtr("key4")}},
+		}
+		want := []config.AwsParameterStoreValueConfig{cfgs[1], cfgs[2], cfgs[3]}
+
+		if got := SelectValues(cfgs, []string{"key2", "key3"}, awsParameterStoreReferenceName); !reflect.DeepEqual(got, want) {
+			t.Errorf("SelectValues() = %v, want %v", got, want)
+		}
+	})
+}
+
 var (
 	mockLoadFunc = func(_ context.Context, key string, decrypt bool) (*string, bool, error) {
 		return stringPtr("value"), false, nil
@@ -67,20 +212,32 @@ var (
 		return stringPtr("value"), true, nil
 	}
 
+	mockLoadStaleFunc = func(_ context.Context, key string, decrypt bool) (*string, error) {
+		return stringPtr("stale"), nil
+	}
+
 	mockSaveFunc = func(_ context.Context, key string, value *string, encrypt bool) error {
 		return nil
 	}
 )
 
 type mockCache struct {
-	load func(ctx context.Context, key string, decrypt bool) (*string, bool, error)
-	save func(ctx context.Context, key string, value *string, encrypt bool) error
+	load      func(ctx context.Context, key string, decrypt bool) (*string, bool, error)
+	loadStale func(ctx context.Context, key string, decrypt bool) (*string, error)
+	save      func(ctx context.Context, key string, value *string, encrypt bool) error
 }
 
 func (m mockCache) Load(ctx context.Context, key string, decrypt bool) (*string, bool, error) {
 	return m.load(ctx, key, decrypt)
 }
 
+func (m mockCache) LoadStale(ctx context.Context, key string, decrypt bool) (*string, error) {
+	if m.loadStale == nil {
+		return nil, nil
+	}
+	return m.loadStale(ctx, key, decrypt)
+}
+
 func (m mockCache) Save(ctx context.Context, key string, value *string, encrypt bool) error {
 	return m.save(ctx, key, value, encrypt)
 }
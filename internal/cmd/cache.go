@@ -0,0 +1,259 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dwango/yashiro/internal/client/cache"
+	"github.com/spf13/cobra"
+)
+
+const cacheExample = `  # list cached values. values themselves are never shown.
+  ysr cache list
+
+  # remove cached values of a key to get them from the external store on next render.
+  ysr cache clear --key /app/prod/db/password
+
+  # remove cached values by key prefix in a namespace. namespace can be a glob pattern.
+  ysr cache clear --prefix /app/prod/ --namespace 'aws_*_123456789012'
+
+  # remove values which are no longer used.
+  ysr cache prune
+
+  # replace the encryption keys and encrypt cached values again.
+  ysr cache rotate-key
+`
+
+func newCacheCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "cache",
+		Short:   "Manage the file base cache",
+		Example: cacheExample,
+	}
+
+	f := cmd.PersistentFlags()
+	f.StringVar(&globalConfig.Global.Cache.File.CachePath, "cache-dir", "", "specify the directory to save the cache files.")
+	f.StringVar((*string)(&globalConfig.Global.Cache.File.Key.Source), "cache-key-source", "",
+		"specify where the key to encrypt the cache is stored. available values: file, keyring, passphrase, aws_kms",
+	)
+
+	cmd.AddCommand(newCacheListCommand())
+	cmd.AddCommand(newCacheClearCommand())
+	cmd.AddCommand(newCachePruneCommand())
+	cmd.AddCommand(newCacheRotateKeyCommand())
+
+	return cmd
+}
+
+// cacheFilter selects cached values by flags.
+type cacheFilter struct {
+	key       string
+	prefix    string
+	namespace string
+}
+
+func (c *cacheFilter) addFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.StringVar(&c.key, "key", "", "select values of the key, including versions, binary secrets and parameters by path of it.")
+	f.StringVar(&c.prefix, "prefix", "", "select values whose keys start with the prefix.")
+	f.StringVar(&c.namespace, "namespace", "", "select values in the namespace. '*' matches any characters including '/', and '?' matches a character.")
+}
+
+func (c *cacheFilter) isEmpty() bool {
+	return len(c.key) == 0 && len(c.prefix) == 0 && len(c.namespace) == 0
+}
+
+func (c *cacheFilter) filter(entries []cache.FileEntry) []cache.FileEntry {
+	namespace := namespacePattern(c.namespace)
+
+	selected := make([]cache.FileEntry, 0, len(entries))
+	for _, e := range entries {
+		if len(c.key) != 0 && !matchKey(e.Key, c.key) {
+			continue
+		}
+		if !strings.HasPrefix(e.Key, c.prefix) {
+			continue
+		}
+		if len(c.namespace) != 0 && !namespace.MatchString(e.Namespace) {
+			continue
+		}
+		selected = append(selected, e)
+	}
+
+	return selected
+}
+
+// matchKey reports whether the cached key is the key or its variant: a selector of Parameter Store
+// ("/name:3"), a version of Secrets Manager ("name?version_stage=AWSPREVIOUS") or Vault
+// ("secret/2/name@3"), a binary secret ("name#binary") or parameters by path ("path:/name/").
+func matchKey(cached, key string) bool {
+	for _, k := range []string{cached, strings.TrimPrefix(cached, "path:")} {
+		if rest, ok := strings.CutPrefix(k, key); ok && (len(rest) == 0 || strings.ContainsRune("?#:@", rune(rest[0]))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// namespacePattern converts the glob pattern of namespaces to a regular expression. Unlike
+// path.Match, '*' matches '/' as well, since namespaces can contain URLs, e.g.
+// "vault_https://vault.example.com:8200".
+func namespacePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+
+	return regexp.MustCompile(b.String())
+}
+
+func newCacheListCommand() *cobra.Command {
+	var filter cacheFilter
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List cached values without the values themselves",
+		Args:    cobra.NoArgs,
+		PreRunE: preLoadCacheConfig,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			entries, err := cache.ListFileEntries(globalConfig.Global.Cache)
+			if err != nil {
+				return err
+			}
+			entries = filter.filter(entries)
+
+			now := time.Now()
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAMESPACE\tKEY\tAGE\tEXPIRES\tSENSITIVE")
+			for _, e := range entries {
+				expires := e.ExpiresAt.Format(time.RFC3339)
+				if e.Expired(now) {
+					expires += " (expired)"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\n", e.Namespace, e.Key, now.Sub(e.SavedAt).Round(time.Second), expires, e.Sensitive)
+			}
+
+			return w.Flush()
+		},
+	}
+	filter.addFlags(cmd)
+
+	return cmd
+}
+
+func newCacheClearCommand() *cobra.Command {
+	var filter cacheFilter
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:     "clear",
+		Short:   "Remove cached values to get them from the external store on next render",
+		Args:    cobra.NoArgs,
+		PreRunE: preLoadCacheConfig,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if filter.isEmpty() && !all {
+				return errors.New("specify --key, --prefix or --namespace, or --all to remove all cached values")
+			}
+
+			entries, err := cache.ListFileEntries(globalConfig.Global.Cache)
+			if err != nil {
+				return err
+			}
+			entries = filter.filter(entries)
+			if err := cache.RemoveFileEntries(globalConfig.Global.Cache, entries); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "removed %d cached values\n", len(entries))
+			return nil
+		},
+	}
+	filter.addFlags(cmd)
+	cmd.Flags().BoolVar(&all, "all", false, "remove all cached values.")
+
+	return cmd
+}
+
+func newCachePruneCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "prune",
+		Short:   "Remove expired cached values which are no longer used",
+		Args:    cobra.NoArgs,
+		PreRunE: preLoadCacheConfig,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			entries, err := cache.PruneFileEntries(globalConfig.Global.Cache)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "removed %d cached values\n", len(entries))
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newCacheRotateKeyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "rotate-key",
+		Short:   "Replace the keys to encrypt the cache and encrypt cached values again",
+		Args:    cobra.NoArgs,
+		PreRunE: preLoadCacheConfig,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			namespaces, err := cache.RotateFileKeys(cmd.Context(), globalConfig.Global.Cache)
+			for _, ns := range namespaces {
+				fmt.Fprintf(cmd.OutOrStdout(), "rotated key of namespace '%s'\n", ns)
+			}
+			if errors.Is(err, cache.ErrUndecryptable) {
+				return fmt.Errorf("%w. check --cache-key-source, or remove the value by 'ysr cache clear' if it is broken", err)
+			}
+
+			return err
+		},
+	}
+
+	return cmd
+}
+
+// preLoadCacheConfig is PreRunE function for cache commands. The config file is loaded if it
+// exists, because only the global section is used and the cache can be managed without it.
+func preLoadCacheConfig(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(configFile); errors.Is(err, fs.ErrNotExist) && !cmd.Flags().Changed("config") {
+		return nil
+	}
+
+	return preLoadConfig(cmd, args)
+}
@@ -0,0 +1,116 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dwango/yashiro/internal/client/cache"
+)
+
+func Test_cacheFilter_filter(t *testing.T) {
+	entries := []cache.FileEntry{
+		{Namespace: "aws_ap-northeast-1_123456789012", Key: "/app/prod/db/password"},
+		{Namespace: "aws_us-east-1_123456789012", Key: "/app/prod/api-key"},
+		{Namespace: "vault_https://vault.example.com:8200", Key: "secret/app/db"},
+		{Namespace: "kubernetes_https://k8s.example.com/prod_production", Key: "secret/shared/db"},
+		{Namespace: "aws_us-east-1_123456789012", Key: "/app/prod/api-key:3"},
+		{Namespace: "aws_us-east-1_123456789012", Key: "app/prod/cert?version_stage=AWSPREVIOUS#binary"},
+		{Namespace: "aws_us-east-1_123456789012", Key: "app/prod/cert#binary"},
+		{Namespace: "aws_us-east-1_123456789012", Key: "path:/app/prod/?recursive"},
+		{Namespace: "aws_us-east-1_123456789012", Key: "/app/prod/api-key-old"},
+	}
+	tests := []struct {
+		name   string
+		filter cacheFilter
+		want   []int
+	}{
+		{
+			name:   "ok: no filter",
+			filter: cacheFilter{},
+			want:   []int{0, 1, 2, 3, 4, 5, 6, 7, 8},
+		},
+		{
+			name:   "ok: key with a selector",
+			filter: cacheFilter{key: "/app/prod/api-key"},
+			want:   []int{1, 4},
+		},
+		{
+			name:   "ok: key with a version and binary",
+			filter: cacheFilter{key: "app/prod/cert"},
+			want:   []int{5, 6},
+		},
+		{
+			name:   "ok: key of parameters by path",
+			filter: cacheFilter{key: "/app/prod/"},
+			want:   []int{7},
+		},
+		{
+			name:   "ok: exact key",
+			filter: cacheFilter{key: "app/prod/cert#binary"},
+			want:   []int{6},
+		},
+		{
+			name:   "ok: prefix",
+			filter: cacheFilter{prefix: "/app/prod/"},
+			want:   []int{0, 1, 4, 8},
+		},
+		{
+			name:   "ok: namespace",
+			filter: cacheFilter{namespace: "aws_*_123456789012"},
+			want:   []int{0, 1, 4, 5, 6, 7, 8},
+		},
+		{
+			name:   "ok: namespace with URL",
+			filter: cacheFilter{namespace: "vault_*"},
+			want:   []int{2},
+		},
+		{
+			name:   "ok: namespace with URL matched across '/'",
+			filter: cacheFilter{namespace: "kubernetes_*_production"},
+			want:   []int{3},
+		},
+		{
+			name:   "ok: namespace with ?",
+			filter: cacheFilter{namespace: "aws_??-east-1_*"},
+			want:   []int{1, 4, 5, 6, 7, 8},
+		},
+		{
+			name:   "ok: meta characters of regular expressions are literal",
+			filter: cacheFilter{namespace: "vault_https://vault.example.com:82.0"},
+			want:   []int{},
+		},
+		{
+			name:   "ok: namespace and prefix",
+			filter: cacheFilter{namespace: "*_ap-northeast-1_*", prefix: "/app/"},
+			want:   []int{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := make([]cache.FileEntry, 0, len(tt.want))
+			for _, i := range tt.want {
+				want = append(want, entries[i])
+			}
+
+			if got := tt.filter.filter(entries); !reflect.DeepEqual(got, want) {
+				t.Errorf("cacheFilter.filter() = %v, want %v", got, want)
+			}
+		})
+	}
+}
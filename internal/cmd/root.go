@@ -42,6 +42,7 @@ func New() *cobra.Command {
 	f.StringVarP(&configFile, "config", "c", config.DefaultConfigFilename, "specify config file.")
 
 	cmd.AddCommand(newTemplateCommand())
+	cmd.AddCommand(newCacheCommand())
 	cmd.AddCommand(newVersionCommand())
 
 	return cmd
@@ -59,6 +60,13 @@ func checkArgsLength(argsReceived int, requiredArgs ...string) error {
 	return nil
 }
 
+func checkMinArgsLength(argsReceived int, requiredArg string) error {
+	if argsReceived < 1 {
+		return fmt.Errorf("this command needs at least 1 argument: %s", requiredArg)
+	}
+	return nil
+}
+
 // preLoadConfig is PreRunE function for cobra.Command. This function preloads config file.
 func preLoadConfig(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
@@ -17,9 +17,14 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/dwango/yashiro/pkg/config"
@@ -35,6 +40,16 @@ const example = `  # specify single file.
 
   # specify multiple files using glob pattern.
   ysr template ./example/*.tmpl
+
+  # helper templates (e.g. _helpers.tpl) in the same directory are loaded automatically.
+  ysr template ./templates/deployment.yaml.tmpl
+
+  # helpers in a walked directory are used also for files in its subdirectories, and helpers in
+  # a subdirectory override them.
+  ysr template ./templates
+
+  # render each file of directories to the output directory. ".tmpl" suffix is removed.
+  ysr template --output-dir ./manifests ./base ./overlays/prod/*.tmpl
 `
 
 var textTypeValues = []string{
@@ -49,59 +64,246 @@ var textTypeValues = []string{
 func newTemplateCommand() *cobra.Command {
 	var ignoreNotFound bool
 	var textType string
+	var dynamicLookup bool
+	var outputDir string
 
 	cmd := cobra.Command{
-		Use:     "template <file>",
+		Use:     "template <file>...",
 		Short:   "Generate a replaced text",
 		Example: example,
 		Args: func(_ *cobra.Command, args []string) error {
-			return checkArgsLength(len(args), "template file")
+			return checkMinArgsLength(len(args), "template file")
 		},
 		PreRunE: preLoadConfig,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
+			files, err := collectFiles(args)
+			if err != nil {
+				return err
+			}
+
 			globalConfig.Global.Cache.Type = config.CacheTypeFile
 			eng, err := engine.New(globalConfig,
 				engine.IgnoreNotFound(ignoreNotFound), engine.TextType(engine.TextTypeOpt(textType)),
+				engine.DynamicLookup(dynamicLookup),
 			)
 			if err != nil {
 				return err
 			}
+			engines := &helperEngines{base: eng, engines: make(map[string]engine.Engine)}
 
-			b, err := readAllFiles(args[0])
-			if err != nil {
-				return err
+			if len(outputDir) != 0 {
+				return renderToDir(ctx, engines.get, files, outputDir)
 			}
 
-			return eng.Render(ctx, string(b), os.Stdout)
+			return renderFiles(ctx, engines.get, files, os.Stdout)
 		},
 	}
 
 	f := cmd.Flags()
 	f.StringVar(&globalConfig.Global.Cache.File.CachePath, "cache-dir", "", "specify the directory to save the cache files.")
 	f.BoolVar(&globalConfig.Global.EnableCache, "enable-cache", false, "enable the file base cache.")
+	f.StringVar((*string)(&globalConfig.Global.Cache.File.Key.Source), "cache-key-source", "",
+		"specify where the key to encrypt the cache is stored. available values: file, keyring, passphrase, aws_kms",
+	)
 	f.StringVar(&textType, "text-type", string(engine.TextTypePlain),
 		fmt.Sprintf("specify the text type after rendering. available values: %s", strings.Join(textTypeValues, ", ")),
 	)
 	f.BoolVar(&ignoreNotFound, "ignore-not-found", false, "ignore values are not found in the external store.")
+	f.BoolVar(&dynamicLookup, "dynamic-lookup", false, "enable ssm, secret and secretJSON functions to get values at render time.")
+	f.StringVar(&outputDir, "output-dir", "", "render each file separately and write it under the directory instead of stdout.")
 
 	return &cmd
 }
 
-func readAllFiles(pattern string) ([]byte, error) {
-	files, err := filepath.Glob(pattern)
+// templateFile is a file to be rendered.
+type templateFile struct {
+	path string
+	// rel is the path relative to the specified directory, or the file name if the file is
+	// specified directly or by glob pattern. It is used as the output path.
+	rel string
+	// helpers are helper files used to render the file, ordered from the farthest directory.
+	helpers []string
+}
+
+// collectFiles returns template files specified by the arguments. An argument is a file, a glob
+// pattern or a directory, which is walked recursively skipping hidden files and directories, e.g.
+// ".git". Helper files of a template file, e.g.
+// "_helpers.tpl", are in its directory and, if the directory is walked, its parent directories up
+// to the specified one.
+func collectFiles(args []string) ([]templateFile, error) {
+	files := make([]templateFile, 0, len(args))
+	helpersInDir := make(map[string][]string)
+	findHelpers := func(dir string) ([]string, error) {
+		if h, ok := helpersInDir[dir]; ok {
+			return h, nil
+		}
+		h, err := filepath.Glob(filepath.Join(dir, "_*.tpl"))
+		if err != nil {
+			return nil, err
+		}
+		helpersInDir[dir] = h
+		return h, nil
+	}
+
+	for _, pattern := range args {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("file not found: '%s'", pattern)
+		}
+
+		for _, m := range matches {
+			// Like a shell, a wildcard does not match a hidden name.
+			if isHidden(m) && !isHidden(pattern) {
+				continue
+			}
+			info, err := os.Stat(m)
+			if err != nil {
+				return nil, err
+			}
+
+			if !info.IsDir() {
+				if !isHelperFile(m) {
+					helpers, err := findHelpers(filepath.Dir(m))
+					if err != nil {
+						return nil, err
+					}
+					files = append(files, templateFile{path: m, rel: filepath.Base(m), helpers: helpers})
+				}
+				continue
+			}
+
+			// helpers of each directory, including those of its parent directories.
+			dirHelpers := make(map[string][]string)
+			err = filepath.WalkDir(m, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if path != m && isHidden(path) {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if d.IsDir() {
+					helpers, err := findHelpers(path)
+					if err != nil {
+						return err
+					}
+					if path != m {
+						helpers = append(slices.Clip(dirHelpers[filepath.Dir(path)]), helpers...)
+					}
+					dirHelpers[path] = helpers
+					return nil
+				}
+				if isHelperFile(path) {
+					return nil
+				}
+				rel, err := filepath.Rel(m, path)
+				if err != nil {
+					return err
+				}
+				files = append(files, templateFile{path: path, rel: rel, helpers: dirHelpers[filepath.Dir(path)]})
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("file not found: '%s'", strings.Join(args, "', '"))
+	}
+
+	return files, nil
+}
+
+// isHidden reports whether the file or directory is hidden, e.g. ".git".
+func isHidden(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// isHelperFile reports whether the file is a helper template which is not rendered by itself.
+func isHelperFile(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasPrefix(name, "_") && strings.HasSuffix(name, ".tpl")
+}
+
+// helperEngines creates an engine for each set of helpers from the base engine, which has no
+// helpers. Engines share the clients of the base engine.
+type helperEngines struct {
+	base    engine.Engine
+	engines map[string]engine.Engine
+}
+
+// get returns the engine with helpers ordered from the farthest directory. Helpers in a directory
+// override those in its parent directories.
+func (e *helperEngines) get(helpers []string) (engine.Engine, error) {
+	if len(helpers) == 0 {
+		return e.base, nil
+	}
+	key := strings.Join(helpers, "\n")
+	if eng, ok := e.engines[key]; ok {
+		return eng, nil
+	}
+
+	options := make([]engine.Option, 0, len(helpers))
+	for i, h := range helpers {
+		if i == 0 || filepath.Dir(h) != filepath.Dir(helpers[i-1]) {
+			options = append(options, engine.HelperScope())
+		}
+		b, err := os.ReadFile(h)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, engine.Helper(h, string(b)))
+	}
+
+	eng, err := engine.With(e.base, options...)
 	if err != nil {
 		return nil, err
 	}
+	e.engines[key] = eng
 
-	if len(files) == 0 {
-		return nil, fmt.Errorf("file not found: '%s'", pattern)
+	return eng, nil
+}
+
+// renderFiles renders files to dest. Consecutive files with the same helpers are concatenated and
+// rendered together.
+func renderFiles(ctx context.Context, getEngine func(helpers []string) (engine.Engine, error), files []templateFile, dest io.Writer) error {
+	for start := 0; start < len(files); {
+		end := start + 1
+		for end < len(files) && slices.Equal(files[end].helpers, files[start].helpers) {
+			end++
+		}
+
+		eng, err := getEngine(files[start].helpers)
+		if err != nil {
+			return err
+		}
+		b, err := readAllFiles(files[start:end])
+		if err != nil {
+			return err
+		}
+		if err := eng.Render(ctx, string(b), dest); err != nil {
+			return err
+		}
+
+		start = end
 	}
 
+	return nil
+}
+
+func readAllFiles(files []templateFile) ([]byte, error) {
 	b := make([]byte, 0, 1024)
 	for _, f := range files {
-		c, err := os.ReadFile(f)
+		c, err := os.ReadFile(f.path)
 		if err != nil {
 			return nil, err
 		}
@@ -110,3 +312,43 @@ func readAllFiles(pattern string) ([]byte, error) {
 
 	return b, nil
 }
+
+// renderToDir renders each file separately and writes it to the same relative path under the
+// output directory. ".tmpl" suffix is removed from the output path. Rendered files may contain
+// secrets, so they are readable only by the owner.
+func renderToDir(ctx context.Context, getEngine func(helpers []string) (engine.Engine, error), files []templateFile, outputDir string) error {
+	outputs := make(map[string]string, len(files))
+	for _, f := range files {
+		out := filepath.Join(outputDir, strings.TrimSuffix(f.rel, ".tmpl"))
+		if prev, ok := outputs[out]; ok {
+			return fmt.Errorf("output file conflicts: '%s' and '%s' are written to '%s'", prev, f.path, out)
+		}
+		outputs[out] = f.path
+	}
+
+	for _, f := range files {
+		eng, err := getEngine(f.helpers)
+		if err != nil {
+			return err
+		}
+		text, err := os.ReadFile(f.path)
+		if err != nil {
+			return err
+		}
+
+		buf := &bytes.Buffer{}
+		if err := eng.Render(ctx, string(text), buf); err != nil {
+			return fmt.Errorf("failed to render '%s': %w", f.path, err)
+		}
+
+		out := filepath.Join(outputDir, strings.TrimSuffix(f.rel, ".tmpl"))
+		if err := os.MkdirAll(filepath.Dir(out), 0700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(out, buf.Bytes(), 0600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
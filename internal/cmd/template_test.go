@@ -0,0 +1,307 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dwango/yashiro/pkg/config"
+	"github.com/dwango/yashiro/pkg/engine"
+	_ "github.com/dwango/yashiro/pkg/provider/file" // engines are created with local files.
+)
+
+// newTestTemplateDir creates template files and helpers in a new directory, and returns it.
+func newTestTemplateDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, name := range []string{
+		"base/_helpers.tpl",
+		"base/deployment.yaml.tmpl",
+		"base/config/app.yaml",
+		"overlays/prod/_helpers.tpl",
+		"overlays/prod/service.yaml.tmpl",
+		"overlays/prod/kustomization.yaml",
+		"overlays/.git/config",
+		"overlays/prod/.env",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func Test_collectFiles(t *testing.T) {
+	dir := newTestTemplateDir(t)
+	join := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantFiles []templateFile
+		wantErr   bool
+	}{
+		{
+			name: "ok: file",
+			args: []string{join("base/deployment.yaml.tmpl")},
+			wantFiles: []templateFile{
+				{path: join("base/deployment.yaml.tmpl"), rel: "deployment.yaml.tmpl", helpers: []string{join("base/_helpers.tpl")}},
+			},
+		},
+		{
+			name: "ok: directory is walked recursively with helpers of parent directories",
+			args: []string{join("base")},
+			wantFiles: []templateFile{
+				{path: join("base/config/app.yaml"), rel: filepath.Join("config", "app.yaml"), helpers: []string{join("base/_helpers.tpl")}},
+				{path: join("base/deployment.yaml.tmpl"), rel: "deployment.yaml.tmpl", helpers: []string{join("base/_helpers.tpl")}},
+			},
+		},
+		{
+			name: "ok: helpers of sibling directories are not shared",
+			args: []string{join("base"), join("overlays/prod/*.tmpl")},
+			wantFiles: []templateFile{
+				{path: join("base/config/app.yaml"), rel: filepath.Join("config", "app.yaml"), helpers: []string{join("base/_helpers.tpl")}},
+				{path: join("base/deployment.yaml.tmpl"), rel: "deployment.yaml.tmpl", helpers: []string{join("base/_helpers.tpl")}},
+				{path: join("overlays/prod/service.yaml.tmpl"), rel: "service.yaml.tmpl", helpers: []string{join("overlays/prod/_helpers.tpl")}},
+			},
+		},
+		{
+			name: "ok: helpers and hidden files are not matched",
+			args: []string{join("overlays/prod/*")},
+			wantFiles: []templateFile{
+				{path: join("overlays/prod/kustomization.yaml"), rel: "kustomization.yaml", helpers: []string{join("overlays/prod/_helpers.tpl")}},
+				{path: join("overlays/prod/service.yaml.tmpl"), rel: "service.yaml.tmpl", helpers: []string{join("overlays/prod/_helpers.tpl")}},
+			},
+		},
+		{
+			name: "ok: parent directory without helpers, and hidden files are skipped",
+			args: []string{join("overlays")},
+			wantFiles: []templateFile{
+				{path: join("overlays/prod/kustomization.yaml"), rel: filepath.Join("prod", "kustomization.yaml"), helpers: []string{join("overlays/prod/_helpers.tpl")}},
+				{path: join("overlays/prod/service.yaml.tmpl"), rel: filepath.Join("prod", "service.yaml.tmpl"), helpers: []string{join("overlays/prod/_helpers.tpl")}},
+			},
+		},
+		{
+			name: "ok: hidden files are used if specified",
+			args: []string{join("overlays/.git"), join("overlays/prod/.e*")},
+			wantFiles: []templateFile{
+				{path: join("overlays/.git/config"), rel: "config"},
+				{path: join("overlays/prod/.env"), rel: ".env", helpers: []string{join("overlays/prod/_helpers.tpl")}},
+			},
+		},
+		{
+			name:    "error: only helpers",
+			args:    []string{join("base/_helpers.tpl")},
+			wantErr: true,
+		},
+		{
+			name:    "error: file not found",
+			args:    []string{join("base"), join("not-found/*.tmpl")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotFiles, err := collectFiles(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("collectFiles() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotFiles, tt.wantFiles) {
+				t.Errorf("collectFiles() gotFiles = %v, want %v", gotFiles, tt.wantFiles)
+			}
+		})
+	}
+}
+
+// mockEngine renders text as it is, or returns the error.
+type mockEngine struct {
+	err error
+}
+
+func (m mockEngine) Render(_ context.Context, text string, dest io.Writer) error {
+	if m.err != nil {
+		return m.err
+	}
+	_, err := io.WriteString(dest, text)
+	return err
+}
+
+func Test_renderToDir(t *testing.T) {
+	dir := newTestTemplateDir(t)
+	join := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		name    string
+		eng     mockEngine
+		files   []templateFile
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "ok: .tmpl is removed and relative paths are kept",
+			files: []templateFile{
+				{path: join("base/config/app.yaml"), rel: filepath.Join("config", "app.yaml")},
+				{path: join("base/deployment.yaml.tmpl"), rel: "deployment.yaml.tmpl"},
+				{path: join("overlays/prod/service.yaml.tmpl"), rel: "service.yaml.tmpl"},
+			},
+			want: map[string]string{
+				"config/app.yaml": "base/config/app.yaml",
+				"deployment.yaml": "base/deployment.yaml.tmpl",
+				"service.yaml":    "overlays/prod/service.yaml.tmpl",
+			},
+		},
+		{
+			name: "error: files are written to the same path",
+			files: []templateFile{
+				{path: join("base/deployment.yaml.tmpl"), rel: "deployment.yaml.tmpl"},
+				{path: join("overlays/prod/service.yaml.tmpl"), rel: "deployment.yaml"},
+			},
+			want:    map[string]string{},
+			wantErr: true,
+		},
+		{
+			name: "error: failed to render",
+			eng:  mockEngine{err: errors.New("render error")},
+			files: []templateFile{
+				{path: join("base/deployment.yaml.tmpl"), rel: "deployment.yaml.tmpl"},
+			},
+			want:    map[string]string{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := filepath.Join(t.TempDir(), "manifests")
+
+			getEngine := func([]string) (engine.Engine, error) { return tt.eng, nil }
+			err := renderToDir(context.Background(), getEngine, tt.files, outputDir)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("renderToDir() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.eng.err != nil && !errors.Is(err, tt.eng.err) {
+				t.Errorf("renderToDir() error = %v, want %v", err, tt.eng.err)
+			}
+
+			got := make(map[string]string)
+			_ = filepath.WalkDir(outputDir, func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				info, err := d.Info()
+				if err != nil {
+					return err
+				}
+				if info.Mode().Perm() != 0600 {
+					t.Errorf("renderToDir() mode of '%s' = %v, want %v", path, info.Mode().Perm(), fs.FileMode(0600))
+				}
+				b, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				rel, _ := filepath.Rel(outputDir, path)
+				got[filepath.ToSlash(rel)] = string(b)
+				return nil
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("renderToDir() wrote %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_helperEngines(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"_helpers.tpl":           `{{ define "labels" }}env: {{ template "env" }}{{ end }}{{ define "env" }}default{{ end }}`,
+		"root.yaml.tmpl":         `{{ template "labels" }}`,
+		"stg/_helpers.tpl":       `{{ define "env" }}stg{{ end }}`,
+		"stg/app.yaml.tmpl":      `{{ template "labels" }}`,
+		"prod/_helpers.tpl":      `{{ define "env" }}prod{{ end }}`,
+		"prod/_names.tpl":        `{{ define "name" }}app{{ end }}`,
+		"prod/app.yaml.tmpl":     `{{ template "name" }} {{ template "labels" }}`,
+		"prod/sub/app.yaml.tmpl": `{{ template "labels" }}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	base, err := engine.New(&config.Config{File: &config.FileConfig{BaseDir: dir}})
+	if err != nil {
+		t.Fatalf("engine.New() error = %v", err)
+	}
+	engines := &helperEngines{base: base, engines: make(map[string]engine.Engine)}
+
+	files, err := collectFiles([]string{dir})
+	if err != nil {
+		t.Fatalf("collectFiles() error = %v", err)
+	}
+
+	// Sibling directories define the same template, and the nearest definition is used.
+	outputDir := t.TempDir()
+	if err := renderToDir(context.Background(), engines.get, files, outputDir); err != nil {
+		t.Fatalf("renderToDir() error = %v", err)
+	}
+	want := map[string]string{
+		"root.yaml":         "env: default",
+		"stg/app.yaml":      "env: stg",
+		"prod/app.yaml":     "app env: prod",
+		"prod/sub/app.yaml": "env: prod",
+	}
+	for name, wantText := range want {
+		b, err := os.ReadFile(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(b); got != wantText {
+			t.Errorf("renderToDir() wrote %v to '%s', want %v", got, name, wantText)
+		}
+	}
+
+	// "prod/sub" has the same helpers as "prod" and shares the engine.
+	if len(engines.engines) != 3 {
+		t.Errorf("helperEngines created %d engines, want 3", len(engines.engines))
+	}
+
+	dest := &bytes.Buffer{}
+	if err := renderFiles(context.Background(), engines.get, files, dest); err != nil {
+		t.Fatalf("renderFiles() error = %v", err)
+	}
+	if got, want := dest.String(), "app env: prodenv: prodenv: defaultenv: stg"; got != want {
+		t.Errorf("renderFiles() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package localfile reads values from local files for the file and sops providers.
+package localfile
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dwango/yashiro/internal/values"
+	"github.com/dwango/yashiro/pkg/config"
+	"github.com/joho/godotenv"
+	"sigs.k8s.io/yaml"
+)
+
+// ReferenceName returns Ref of a file value config. If Ref is not set, reference names are
+// top-level keys of the file, which are known only after reading it.
+func ReferenceName(v config.FileValueConfig) (string, bool) {
+	if v.Ref == nil || len(*v.Ref) == 0 {
+		return "", false
+	}
+
+	return *v.Ref, true
+}
+
+// ResolvePath returns path resolved from baseDir if path is relative.
+func ResolvePath(baseDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// SetValues sets content to values. If Ref is empty, top-level keys of content are set.
+func SetValues(values values.Values, v config.FileValueConfig, content map[string]any) {
+	if v.Ref != nil && len(*v.Ref) != 0 {
+		values[*v.Ref] = content
+		return
+	}
+
+	for key, val := range content {
+		values[key] = val
+	}
+}
+
+// Parse parses JSON, YAML or dotenv as a map.
+func Parse(b []byte, format config.FileFormat) (map[string]any, error) {
+	content := make(map[string]any)
+	switch format {
+	case config.FileFormatJSON, config.FileFormatYAML:
+		// JSON is a subset of YAML.
+		if err := yaml.Unmarshal(b, &content); err != nil {
+			return nil, err
+		}
+	case config.FileFormatDotenv:
+		env, err := godotenv.Parse(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		for key, val := range env {
+			content[key] = val
+		}
+	default:
+		return nil, fmt.Errorf("invalid file format: %s", format)
+	}
+
+	return content, nil
+}
+
+// FormatFromPath infers the format from the file name. If it cannot be inferred, it returns
+// FileFormatUnspecified.
+func FormatFromPath(path string) config.FileFormat {
+	base := filepath.Base(path)
+	switch {
+	case strings.HasSuffix(base, ".json"):
+		return config.FileFormatJSON
+	case strings.HasSuffix(base, ".yaml"), strings.HasSuffix(base, ".yml"):
+		return config.FileFormatYAML
+	case strings.HasPrefix(base, ".env"), strings.HasSuffix(base, ".env"):
+		return config.FileFormatDotenv
+	default:
+		return config.FileFormatUnspecified
+	}
+}
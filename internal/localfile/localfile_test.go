@@ -0,0 +1,94 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package localfile
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dwango/yashiro/pkg/config"
+)
+
+func TestReferenceName(t *testing.T) {
+	ref := "key"
+	tests := []struct {
+		name   string
+		v      config.FileValueConfig
+		want   string
+		wantOk bool
+	}{
+		{name: "ok: ref", v: config.FileValueConfig{Path: "a.json", Ref: &ref}, want: "key", wantOk: true},
+		{name: "ok: top-level keys", v: config.FileValueConfig{Path: "a.json"}, want: "", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOk := ReferenceName(tt.v)
+			if got != tt.want || gotOk != tt.wantOk {
+				t.Errorf("ReferenceName() = %v, %v, want %v, %v", got, gotOk, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       string
+		format  config.FileFormat
+		want    map[string]any
+		wantErr bool
+	}{
+		{name: "ok: json", b: `{"key":"value"}`, format: config.FileFormatJSON, want: map[string]any{"key": "value"}},
+		{name: "ok: yaml", b: "key: value\n", format: config.FileFormatYAML, want: map[string]any{"key": "value"}},
+		{name: "ok: dotenv", b: "KEY=value\n", format: config.FileFormatDotenv, want: map[string]any{"KEY": "value"}},
+		{name: "error: unspecified format", b: "key=value", format: config.FileFormatUnspecified, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.b), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want config.FileFormat
+	}{
+		{path: "values.json", want: config.FileFormatJSON},
+		{path: "values.yaml", want: config.FileFormatYAML},
+		{path: "values.yml", want: config.FileFormatYAML},
+		{path: ".env", want: config.FileFormatDotenv},
+		{path: "dir/.env.local", want: config.FileFormatDotenv},
+		{path: "local.env", want: config.FileFormatDotenv},
+		{path: "values.txt", want: config.FileFormatUnspecified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := FormatFromPath(tt.path); got != tt.want {
+				t.Errorf("FormatFromPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/dwango/yashiro/pkg/config"
 )
@@ -27,6 +28,7 @@ import (
 var (
 	ErrValueIsEmpty = errors.New("value is empty")
 	ErrInvalidJSON  = errors.New("invalid json string")
+	ErrConflictPath = errors.New("value conflicts with another value")
 )
 
 // Values are stored values from external stores.
@@ -43,17 +45,65 @@ func (v Values) SetValue(cfg config.Value, value *string) error {
 		v = make(Values)
 	}
 
-	var val any
-	if cfg.GetIsJSON() {
-		val = make(map[string]any)
-		if err := json.Unmarshal([]byte(*value), &val); err != nil {
-			return fmt.Errorf("%w: %w", ErrInvalidJSON, err)
-		}
-	} else {
-		val = *value
+	val, err := decodeValue(cfg, *value)
+	if err != nil {
+		return err
 	}
 
 	v[cfg.GetReferenceName()] = val
 
 	return nil
 }
+
+// SetValueByPath sets the getting value from external stores to the nested map. Maps are
+// created along keys, and the value is set to the last key. If a key is already set as a
+// non-map value, returns ErrConflictPath.
+func (v Values) SetValueByPath(cfg config.Value, keys []string, value *string) error {
+	if value == nil || len(*value) == 0 {
+		return ErrValueIsEmpty
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("%w: keys are empty", ErrConflictPath)
+	}
+
+	val, err := decodeValue(cfg, *value)
+	if err != nil {
+		return err
+	}
+
+	m := map[string]any(v)
+	for i, key := range keys[:len(keys)-1] {
+		child, ok := m[key]
+		if !ok {
+			child = make(map[string]any)
+			m[key] = child
+		}
+
+		childMap, ok := child.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%w: '%s'", ErrConflictPath, strings.Join(keys[:i+1], "."))
+		}
+		m = childMap
+	}
+
+	last := keys[len(keys)-1]
+	if _, ok := m[last]; ok {
+		return fmt.Errorf("%w: '%s'", ErrConflictPath, strings.Join(keys, "."))
+	}
+	m[last] = val
+
+	return nil
+}
+
+func decodeValue(cfg config.Value, value string) (any, error) {
+	if !cfg.GetIsJSON() {
+		return value, nil
+	}
+
+	var val any = make(map[string]any)
+	if err := json.Unmarshal([]byte(value), &val); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidJSON, err)
+	}
+
+	return val, nil
+}
@@ -18,6 +18,7 @@ package values
 
 import (
 	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/dwango/yashiro/pkg/config"
@@ -91,3 +92,82 @@ func TestValues_SetValue(t *testing.T) {
 		})
 	}
 }
+
+func TestValues_SetValueByPath(t *testing.T) {
+	type args struct {
+		cfg   config.Value
+		keys  []string
+		value *string
+	}
+	returnStrPtr := func(s string) *string { return &s }
+	tests := []struct {
+		name    string
+		v       Values
+		args    args
+		want    Values
+		wantErr error
+	}{
+		{
+			name: "ok",
+			v:    Values{"app": map[string]any{"db": map[string]any{"user": "user"}}},
+			args: args{
+				cfg:   mockConfigValue{isJSON: false},
+				keys:  []string{"app", "db", "host"},
+				value: returnStrPtr("localhost"),
+			},
+			want: Values{"app": map[string]any{"db": map[string]any{"user": "user", "host": "localhost"}}},
+		},
+		{
+			name: "ok: json",
+			v:    make(Values),
+			args: args{
+				cfg:   mockConfigValue{isJSON: true},
+				keys:  []string{"app", "db"},
+				value: returnStrPtr(`{"host":"localhost"}`),
+			},
+			want: Values{"app": map[string]any{"db": map[string]any{"host": "localhost"}}},
+		},
+		{
+			name: "error: value is empty",
+			v:    make(Values),
+			args: args{
+				cfg:   mockConfigValue{isJSON: false},
+				keys:  []string{"app"},
+				value: returnStrPtr(""),
+			},
+			wantErr: ErrValueIsEmpty,
+		},
+		{
+			name: "error: parent is not a map",
+			v:    Values{"app": "value"},
+			args: args{
+				cfg:   mockConfigValue{isJSON: false},
+				keys:  []string{"app", "db"},
+				value: returnStrPtr("value"),
+			},
+			wantErr: ErrConflictPath,
+		},
+		{
+			name: "error: already set",
+			v:    Values{"app": map[string]any{"db": "value"}},
+			args: args{
+				cfg:   mockConfigValue{isJSON: false},
+				keys:  []string{"app", "db"},
+				value: returnStrPtr("value"),
+			},
+			wantErr: ErrConflictPath,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.v.SetValueByPath(tt.args.cfg, tt.args.keys, tt.args.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Values.SetValueByPath() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(tt.v, tt.want) {
+				t.Errorf("Values.SetValueByPath() = %v, want %v", tt.v, tt.want)
+			}
+		})
+	}
+}
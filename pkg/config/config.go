@@ -18,8 +18,12 @@ package config
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -33,6 +37,17 @@ const DefaultConfigFilename = "./yashiro.yaml"
 type Config struct {
 	Global GlobalConfig `json:"global,omitempty"`
 	Aws    *AwsConfig   `json:"aws,omitempty"`
+	Vault  *VaultConfig `json:"vault,omitempty"`
+	Gcp    *GcpConfig   `json:"gcp,omitempty"`
+	Azure  *AzureConfig `json:"azure,omitempty"`
+
+	Kubernetes *KubernetesConfig `json:"kubernetes,omitempty"`
+	File       *FileConfig       `json:"file,omitempty"`
+	Sops       *SopsConfig       `json:"sops,omitempty"`
+
+	// Providers are configurations of providers registered by users. A key is the name of a
+	// registered provider, and a value is decoded by ProviderConfig.
+	Providers map[string]json.RawMessage `json:"providers,omitempty"`
 }
 
 type GlobalConfig struct {
@@ -49,24 +64,103 @@ const (
 )
 
 type CacheConfig struct {
-	Type           CacheType       `json:"type"`
-	ExpireDuration Duration        `json:"expire_duration,omitempty"`
-	File           FileCacheConfig `json:"file,omitempty"`
+	Type           CacheType          `json:"type"`
+	ExpireDuration Duration           `json:"expire_duration,omitempty"`
+	StaleIfError   StaleIfErrorConfig `json:"stale_if_error,omitempty"`
+	File           FileCacheConfig    `json:"file,omitempty"`
 }
 
 const DefaultExpireDuration time.Duration = 30 * 24 * time.Hour // 30 days
 
+// StaleIfErrorConfig is the policy to use an expired cached value when getting the value from the
+// external store fails with a transient error, e.g. throttling, network errors and server errors.
+type StaleIfErrorConfig struct {
+	Enabled bool     `json:"enabled"`
+	MaxAge  Duration `json:"max_age,omitempty"` // how long a value can be used after it expires, default: DefaultStaleIfErrorMaxAge
+}
+
+const DefaultStaleIfErrorMaxAge time.Duration = 24 * time.Hour
+
 type FileCacheConfig struct {
-	CachePath string `json:"cache_path,omitempty"`
+	CachePath string             `json:"cache_path,omitempty"`
+	Key       FileCacheKeyConfig `json:"key,omitempty"`
+}
+
+// FileCacheKeySource is the source of the key to encrypt cache files.
+type FileCacheKeySource string
+
+const (
+	FileCacheKeySourceUnspecified FileCacheKeySource = ""
+	FileCacheKeySourceFile        FileCacheKeySource = "file"       // default, key file in the cache directory
+	FileCacheKeySourceKeyring     FileCacheKeySource = "keyring"    // OS keyring, e.g. Secret Service on Linux
+	FileCacheKeySourcePassphrase  FileCacheKeySource = "passphrase" // passphrase in an environment variable
+	FileCacheKeySourceAwsKms      FileCacheKeySource = "aws_kms"    // data key wrapped by AWS KMS
+)
+
+const (
+	DefaultFileCacheKeyringService = "yashiro"
+	DefaultFileCachePassphraseEnv  = "YASHIRO_CACHE_PASSPHRASE"
+)
+
+// FileCacheKeyConfig is the configuration of the key to encrypt cache files.
+type FileCacheKeyConfig struct {
+	Source         FileCacheKeySource    `json:"source,omitempty"`
+	KeyringService string                `json:"keyring_service,omitempty"` // default: DefaultFileCacheKeyringService
+	PassphraseEnv  string                `json:"passphrase_env,omitempty"`  // default: DefaultFileCachePassphraseEnv
+	AwsKms         FileCacheAwsKmsConfig `json:"aws_kms,omitempty"`
+}
+
+// FileCacheAwsKmsConfig is the configuration of AWS KMS which wraps the data key of cache files.
+// The wrapped data key is stored in the cache directory.
+type FileCacheAwsKmsConfig struct {
+	KeyID    string `json:"key_id"`
+	Region   string `json:"region,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
 }
 
 // AwsConfig is AWS service configuration.
 type AwsConfig struct {
 	ParameterStoreValues []AwsParameterStoreValueConfig `json:"parameter_store,omitempty"`
-	SecretsManagerValues []ValueConfig                  `json:"secrets_manager,omitempty"`
+	SecretsManagerValues []AwsSecretsManagerValueConfig `json:"secrets_manager,omitempty"`
+	MaxConcurrency       int                            `json:"max_concurrency,omitempty"`
+	Endpoints            AwsEndpointsConfig             `json:"endpoints,omitempty"`
+	CacheNamespace       string                         `json:"cache_namespace,omitempty"`
+	AccountLookupTimeout Duration                       `json:"account_lookup_timeout,omitempty"`
 	SdkConfig            *aws.Config                    `json:"-"`
 }
 
+// DefaultAwsAccountLookupTimeout is the default timeout of looking up the AWS account ID for the
+// cache namespace.
+const DefaultAwsAccountLookupTimeout time.Duration = 10 * time.Second
+
+// AwsEndpointsConfig overrides endpoints of AWS services, e.g. LocalStack or VPC endpoints. If a
+// field is empty, the default endpoint is used.
+type AwsEndpointsConfig struct {
+	Ssm            string `json:"ssm,omitempty"`
+	SecretsManager string `json:"secrets_manager,omitempty"`
+	Sts            string `json:"sts,omitempty"`
+}
+
+// DefaultAwsMaxConcurrency is the default maximum number of concurrent requests to AWS.
+const DefaultAwsMaxConcurrency = 10
+
+// AwsSourceConfig is an AWS account and region which a value is got from. Empty fields are
+// taken from AwsConfig.SdkConfig.
+//
+// If Profile is set, the shared configuration profile is loaded instead of SdkConfig. If RoleArn
+// is set, the role is assumed with ExternalID.
+//
+// CacheNamespace separates cached values of the source. If it is empty, the namespace is derived
+// from RoleArn, Profile or AwsConfig.CacheNamespace in this order, or the AWS account ID of the
+// credentials is used, which is looked up by STS when the cache is used for the first time.
+type AwsSourceConfig struct {
+	Region         string `json:"region,omitempty"`
+	Profile        string `json:"profile,omitempty"`
+	RoleArn        string `json:"role_arn,omitempty"`
+	ExternalID     string `json:"external_id,omitempty"`
+	CacheNamespace string `json:"cache_namespace,omitempty"`
+}
+
 // ValueConfig is a value of external store configuration.
 type ValueConfig struct {
 	Name   string  `json:"name"`
@@ -76,9 +170,215 @@ type ValueConfig struct {
 
 // AwsParameterStoreValueConfig is a AWS Systems Manager Parameter Store configuration. This
 // is extended ValueConfig for parameter decryption.
+//
+// If Path is set, Name is ignored and all parameters under the path are set as a nested map
+// keyed by the path segments. The map is referenced by Ref, or its top-level keys are set as
+// values if Ref is empty. IsJSON is applied to each parameter.
+//
+// Version or Label pins the parameter to the version or the label. They can not be set at the
+// same time, and are ignored if Path is set.
 type AwsParameterStoreValueConfig struct {
 	ValueConfig
-	Decryption *bool `json:"decryption,omitempty"`
+	AwsSourceConfig
+	Decryption *bool  `json:"decryption,omitempty"`
+	Path       string `json:"path,omitempty"`
+	Recursive  bool   `json:"recursive,omitempty"`
+	Version    int64  `json:"version,omitempty"`
+	Label      string `json:"label,omitempty"`
+}
+
+// GetParameterName returns the parameter name with the selector of Version or Label, e.g.
+// "/name:3" or "/name:label". If neither is set, returns Name.
+func (c AwsParameterStoreValueConfig) GetParameterName() string {
+	if c.Version != 0 {
+		return c.Name + ":" + strconv.FormatInt(c.Version, 10)
+	}
+	if len(c.Label) != 0 {
+		return c.Name + ":" + c.Label
+	}
+
+	return c.Name
+}
+
+// AwsSecretBinaryEncoding is an encoding of a binary secret of AWS Secrets Manager.
+type AwsSecretBinaryEncoding string
+
+const (
+	AwsSecretBinaryEncodingUnspecified AwsSecretBinaryEncoding = ""
+	AwsSecretBinaryEncodingBase64      AwsSecretBinaryEncoding = "base64" // default
+	AwsSecretBinaryEncodingRaw         AwsSecretBinaryEncoding = "raw"    // e.g. used with b64enc
+)
+
+// AwsSecretsManagerValueConfig is a AWS Secrets Manager configuration. This is extended
+// ValueConfig for versions and binary secrets.
+//
+// VersionStage or VersionID pins the secret to the version, e.g. AWSPREVIOUS. If the secret is
+// binary, the value is encoded by BinaryEncoding.
+type AwsSecretsManagerValueConfig struct {
+	ValueConfig
+	AwsSourceConfig
+	VersionStage   string                  `json:"version_stage,omitempty"`
+	VersionID      string                  `json:"version_id,omitempty"`
+	BinaryEncoding AwsSecretBinaryEncoding `json:"binary_encoding,omitempty"`
+}
+
+// VaultConfig is HashiCorp Vault configuration. If Address is empty, VAULT_ADDR environment
+// variable is used. Relative paths are resolved from BaseDir like FileConfig.
+type VaultConfig struct {
+	Address   string               `json:"address,omitempty"`
+	Namespace string               `json:"namespace,omitempty"`
+	Auth      VaultAuthConfig      `json:"auth,omitempty"`
+	KvValues  []VaultKvValueConfig `json:"kv,omitempty"`
+	BaseDir   string               `json:"-"`
+}
+
+type VaultAuthMethod string
+
+const (
+	VaultAuthMethodToken      VaultAuthMethod = "token" // default
+	VaultAuthMethodAppRole    VaultAuthMethod = "approle"
+	VaultAuthMethodKubernetes VaultAuthMethod = "kubernetes"
+)
+
+// VaultAuthConfig is a Vault authentication configuration. MountPath defaults to the name of
+// the method.
+type VaultAuthConfig struct {
+	Method    VaultAuthMethod `json:"method,omitempty"`
+	MountPath string          `json:"mount_path,omitempty"`
+
+	// Token is used by token method. If empty, VAULT_TOKEN environment variable is used.
+	Token string `json:"token,omitempty"`
+
+	// RoleID and SecretID are used by approle method. If SecretID is empty, it is read from the
+	// environment variable SecretIDEnv or the file SecretIDPath, so that it is not committed with
+	// the configuration file.
+	RoleID       string `json:"role_id,omitempty"`
+	SecretID     string `json:"secret_id,omitempty"`
+	SecretIDEnv  string `json:"secret_id_env,omitempty"`
+	SecretIDPath string `json:"secret_id_path,omitempty"`
+
+	// Role and ServiceAccountTokenPath are used by kubernetes method.
+	Role                    string `json:"role,omitempty"`
+	ServiceAccountTokenPath string `json:"service_account_token_path,omitempty"`
+}
+
+const DefaultVaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// VaultKvValueConfig is a Vault KV secrets engine configuration. This is extended ValueConfig,
+// and Name is a path of the secret in the mount. If Key is empty, all data of the secret is set
+// as JSON string.
+type VaultKvValueConfig struct {
+	ValueConfig
+	Mount         string `json:"mount,omitempty"`
+	EngineVersion int    `json:"engine_version,omitempty"`
+	Key           string `json:"key,omitempty"`
+
+	// Version pins the version of the secret. This is only available with KV version 2.
+	Version int `json:"version,omitempty"`
+}
+
+const (
+	DefaultVaultKvMount         = "secret"
+	DefaultVaultKvEngineVersion = 2
+)
+
+// GcpConfig is Google Cloud service configuration. Credentials are found by Application Default
+// Credentials.
+type GcpConfig struct {
+	// Project is used for secrets whose name is not a resource name.
+	Project string `json:"project,omitempty"`
+
+	// Endpoint overrides the Secret Manager endpoint. If Insecure is true, the client connects to
+	// the endpoint without TLS and authentication. This is intended for local fake servers.
+	Endpoint string `json:"endpoint,omitempty"`
+	Insecure bool   `json:"insecure,omitempty"`
+
+	SecretManagerValues []GcpSecretManagerValueConfig `json:"secret_manager,omitempty"`
+}
+
+// GcpSecretManagerValueConfig is a Google Cloud Secret Manager configuration. This is extended
+// ValueConfig, and Name is a secret ID or a resource name formatted as
+// "projects/*/secrets/*" or "projects/*/secrets/*/versions/*".
+type GcpSecretManagerValueConfig struct {
+	ValueConfig
+	Version string `json:"version,omitempty"`
+}
+
+const DefaultGcpSecretVersion = "latest"
+
+// AzureConfig is Azure service configuration. Credentials are found by the default Azure
+// credential chain (environment, workload identity, managed identity and Azure CLI).
+type AzureConfig struct {
+	// VaultURL is used for values whose vault URL is not specified.
+	VaultURL       string                     `json:"vault_url,omitempty"`
+	KeyVaultValues []AzureKeyVaultValueConfig `json:"key_vault,omitempty"`
+}
+
+// AzureKeyVaultValueConfig is an Azure Key Vault secret configuration. This is extended
+// ValueConfig for the vault URL and the version of the secret. If Version is empty, the latest
+// version is used.
+type AzureKeyVaultValueConfig struct {
+	ValueConfig
+	VaultURL string `json:"vault_url,omitempty"`
+	Version  string `json:"version,omitempty"`
+}
+
+// KubernetesConfig is Kubernetes configuration. If Kubeconfig is empty, KUBECONFIG environment
+// variable or ~/.kube/config is used, and in-cluster config is used when they do not exist.
+// Leading "~" of Kubeconfig is expanded to the home directory, and a relative path is resolved from
+// BaseDir like FileConfig.
+type KubernetesConfig struct {
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	Context    string `json:"context,omitempty"`
+	BaseDir    string `json:"-"`
+
+	// Namespace is used for values whose namespace is not specified. If empty, the namespace of
+	// the context is used.
+	Namespace string `json:"namespace,omitempty"`
+
+	SecretValues    []KubernetesValueConfig `json:"secrets,omitempty"`
+	ConfigMapValues []KubernetesValueConfig `json:"config_maps,omitempty"`
+}
+
+// KubernetesValueConfig is a Kubernetes Secret or ConfigMap configuration. This is extended
+// ValueConfig, and Name is the name of the object. If Key is empty, all data of the object is set
+// as map[string]any.
+type KubernetesValueConfig struct {
+	ValueConfig
+	Namespace string `json:"namespace,omitempty"`
+	Key       string `json:"key,omitempty"`
+}
+
+// FileConfig is local file configuration. Relative paths are resolved from BaseDir, which is
+// set to the directory of the configuration file by LoadFromFile.
+type FileConfig struct {
+	Values  []FileValueConfig `json:"values,omitempty"`
+	BaseDir string            `json:"-"`
+}
+
+type FileFormat string
+
+const (
+	FileFormatUnspecified FileFormat = "" // inferred from the extension
+	FileFormatJSON        FileFormat = "json"
+	FileFormatYAML        FileFormat = "yaml"
+	FileFormatDotenv      FileFormat = "dotenv"
+)
+
+// FileValueConfig is a local file configuration. If Ref is empty, top-level keys of the file
+// are set as values. Otherwise, all content of the file is set as a map referenced by Ref.
+type FileValueConfig struct {
+	Path   string     `json:"path"`
+	Ref    *string    `json:"ref,omitempty"`
+	Format FileFormat `json:"format,omitempty"`
+}
+
+// SopsConfig is SOPS encrypted file configuration. Keys to decrypt files are found in the same
+// way as the sops command, e.g. SOPS_AGE_KEY, SOPS_AGE_KEY_FILE, GnuPG keyring and AWS
+// credentials for KMS. Relative paths are resolved from BaseDir like FileConfig.
+type SopsConfig struct {
+	Values  []FileValueConfig `json:"values,omitempty"`
+	BaseDir string            `json:"-"`
 }
 
 // LoadFromFile sets Config values according to a file. The configuration file is assumed to
@@ -101,9 +401,37 @@ func (c *Config) LoadFromFile(ctx context.Context, filename string) error {
 		c.Aws.SdkConfig = &awsCfg
 	}
 
+	if c.File != nil && len(c.File.BaseDir) == 0 {
+		c.File.BaseDir = filepath.Dir(filename)
+	}
+	if c.Sops != nil && len(c.Sops.BaseDir) == 0 {
+		c.Sops.BaseDir = filepath.Dir(filename)
+	}
+	if c.Vault != nil && len(c.Vault.BaseDir) == 0 {
+		c.Vault.BaseDir = filepath.Dir(filename)
+	}
+	if c.Kubernetes != nil && len(c.Kubernetes.BaseDir) == 0 {
+		c.Kubernetes.BaseDir = filepath.Dir(filename)
+	}
+
 	return nil
 }
 
+// ProviderConfig decodes the configuration of the named provider into v. If the provider is
+// not configured, returns false.
+func (c *Config) ProviderConfig(name string, v any) (bool, error) {
+	raw, ok := c.Providers[name]
+	if !ok {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("invalid config of provider '%s': %w", name, err)
+	}
+
+	return true, nil
+}
+
 // Value is interface of external store value.
 type Value interface {
 	GetReferenceName() string
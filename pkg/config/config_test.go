@@ -0,0 +1,104 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestConfig_ProviderConfig(t *testing.T) {
+	type providerConfig struct {
+		Names []string `json:"names"`
+	}
+	tests := []struct {
+		name      string
+		providers map[string]json.RawMessage
+		want      providerConfig
+		wantOk    bool
+		wantErr   bool
+	}{
+		{
+			name: "ok",
+			providers: map[string]json.RawMessage{
+				"test": json.RawMessage(`{"names": ["foo"]}`),
+			},
+			want:   providerConfig{Names: []string{"foo"}},
+			wantOk: true,
+		},
+		{
+			name:      "ok: not configured",
+			providers: nil,
+			wantOk:    false,
+		},
+		{
+			name: "error: invalid config",
+			providers: map[string]json.RawMessage{
+				"test": json.RawMessage(`{"names": "foo"}`),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Providers: tt.providers}
+			var got providerConfig
+			gotOk, err := c.ProviderConfig("test", &got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Config.ProviderConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotOk != tt.wantOk {
+				t.Errorf("Config.ProviderConfig() ok = %v, want %v", gotOk, tt.wantOk)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Config.ProviderConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAwsParameterStoreValueConfig_GetParameterName(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  AwsParameterStoreValueConfig
+		want string
+	}{
+		{
+			name: "ok: no selector",
+			cfg:  AwsParameterStoreValueConfig{ValueConfig: ValueConfig{Name: "/name"}},
+			want: "/name",
+		},
+		{
+			name: "ok: version",
+			cfg:  AwsParameterStoreValueConfig{ValueConfig: ValueConfig{Name: "/name"}, Version: 3},
+			want: "/name:3",
+		},
+		{
+			name: "ok: label",
+			cfg:  AwsParameterStoreValueConfig{ValueConfig: ValueConfig{Name: "/name"}, Label: "prod"},
+			want: "/name:prod",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.GetParameterName(); got != tt.want {
+				t.Errorf("AwsParameterStoreValueConfig.GetParameterName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
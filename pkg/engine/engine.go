@@ -22,18 +22,27 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"text/template"
+	"text/template/parse"
 
 	"github.com/dwango/yashiro/internal/client"
+	"github.com/dwango/yashiro/internal/values"
 	"github.com/dwango/yashiro/pkg/config"
 	"github.com/dwango/yashiro/pkg/engine/encoding"
+	"github.com/dwango/yashiro/pkg/provider"
 )
 
 var (
-	ErrRendering = errors.New("failed to render")
+	ErrRendering          = errors.New("failed to render")
+	ErrLookupNotSupported = errors.New("no configured store supports dynamic lookup")
+	ErrLookupNotFound     = errors.New("value is not found in the external store")
+	ErrIncludeDepth       = errors.New("include or tpl is nested too deeply")
+	ErrHelperConflict     = errors.New("template is defined in multiple helpers")
 )
 
-// Engine is a template engine.
+// Engine is a template engine. Render is safe for concurrent use, and each call renders text on
+// an isolated template.
 type Engine interface {
 	Render(ctx context.Context, text string, dest io.Writer) error
 }
@@ -41,12 +50,13 @@ type Engine interface {
 type engine struct {
 	client           client.Client
 	encodeAndDecoder encoding.EncodeAndDecoder
-	template         *template.Template
+	template         *template.Template // base template with helpers, cloned for each render
 	option           *opts
+	lookuper         client.Lookuper // nil if dynamic lookup is disabled
 }
 
 func New(cfg *config.Config, option ...Option) (Engine, error) {
-	opts := defaultOpts
+	opts := defaultOpts()
 	for _, o := range option {
 		o(opts)
 	}
@@ -56,10 +66,33 @@ func New(cfg *config.Config, option ...Option) (Engine, error) {
 		return nil, err
 	}
 
+	return newEngine(cli, opts)
+}
+
+// With returns a new Engine with the options of eng and additional options. The new Engine
+// shares the external stores clients with eng, so that they and their caches are not created
+// again, e.g. for templates which use different helpers.
+func With(eng Engine, option ...Option) (Engine, error) {
+	e, ok := eng.(*engine)
+	if !ok {
+		return nil, fmt.Errorf("engine is not created by New: %T", eng)
+	}
+
+	opts := *e.option
+	opts.Helpers = slices.Clip(opts.Helpers)
+	for _, o := range option {
+		o(&opts)
+	}
+
+	return newEngine(e.client, &opts)
+}
+
+func newEngine(cli client.Client, opts *opts) (*engine, error) {
 	var encAndDec encoding.EncodeAndDecoder
 	if opts.TextType == TextTypePlain {
 		encAndDec = &noOpEncodeAndDecoder{}
 	} else {
+		var err error
 		encAndDec, err = encoding.NewEncodeAndDecoder(opts.TextType)
 		if err != nil {
 			return nil, err
@@ -68,30 +101,110 @@ func New(cfg *config.Config, option ...Option) (Engine, error) {
 
 	tmpl := template.New("yashiro").Option("missingkey=error").Funcs(funcMap())
 
+	var lookuper client.Lookuper
+	if opts.DynamicLookup {
+		l, ok := client.FindLookuper(cli)
+		if !ok {
+			return nil, ErrLookupNotSupported
+		}
+		lookuper = l
+		// Functions are bound to the context of each render, so these are only for parsing.
+		tmpl.Funcs(lookupFuncMap(context.Background(), lookuper, opts.IgnoreNotFound))
+	}
+
+	// Helpers can use include and tpl, which are bound to the template of each render.
+	tmpl.Funcs(templateFuncMap(tmpl))
+	if err := parseHelpers(tmpl, opts.Helpers); err != nil {
+		return nil, err
+	}
+
 	return &engine{
 		client:           cli,
 		encodeAndDecoder: encAndDec,
 		template:         tmpl,
 		option:           opts,
+		lookuper:         lookuper,
 	}, nil
 }
 
+// parseHelpers parses helpers into tmpl in order. A template defined in a helper replaces the one
+// with the same name in an earlier scope. In the same scope, it would silently replace the one in
+// another helper, so it is an error.
+func parseHelpers(tmpl *template.Template, helpers []helper) error {
+	definedIn := make(map[string]helper)
+	for _, h := range helpers {
+		if _, err := tmpl.New(h.name).Parse(h.text); err != nil {
+			return fmt.Errorf("%w: helper '%s': %w", ErrRendering, h.name, err)
+		}
+
+		// Parse again without functions to get the names of templates defined in the helper.
+		trees := make(map[string]*parse.Tree)
+		t := parse.New(h.name)
+		t.Mode = parse.SkipFuncCheck
+		if _, err := t.Parse(h.text, "", "", trees); err != nil {
+			return fmt.Errorf("%w: helper '%s': %w", ErrRendering, h.name, err)
+		}
+		for name := range trees {
+			if name == h.name {
+				continue
+			}
+			if prev, ok := definedIn[name]; ok && prev.scope == h.scope {
+				return fmt.Errorf("%w: '%s' is defined in '%s' and '%s'", ErrHelperConflict, name, prev.name, h.name)
+			}
+			definedIn[name] = h
+		}
+	}
+
+	return nil
+}
+
 func (e engine) Render(ctx context.Context, text string, dest io.Writer) error {
-	values, err := e.client.GetValues(ctx, e.option.IgnoreNotFound)
+	tmpl, err := e.parse(text)
+	if err != nil {
+		return err
+	}
+
+	vals, err := e.getValues(ctx, tmpl)
 	if err != nil {
 		return err
 	}
 
-	return e.render(text, dest, values)
+	if e.lookuper != nil {
+		tmpl.Funcs(lookupFuncMap(ctx, e.lookuper, e.option.IgnoreNotFound))
+	}
+
+	return e.execute(tmpl, dest, vals)
 }
 
-func (e engine) render(text string, dest io.Writer, data any) error {
-	if _, err := e.template.Parse(text); err != nil {
-		return fmt.Errorf("%w: %w", ErrRendering, err)
+// parse parses text on a clone of the base template, so that templates defined in text do not
+// leak into other renders.
+func (e engine) parse(text string) (*template.Template, error) {
+	tmpl, err := e.template.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRendering, err)
+	}
+	tmpl.Funcs(templateFuncMap(tmpl))
+	if _, err := tmpl.Parse(text); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRendering, err)
 	}
 
+	return tmpl, nil
+}
+
+// getValues gets only values which tmpl references. If referenced values cannot be determined,
+// it gets all values.
+func (e engine) getValues(ctx context.Context, tmpl *template.Template) (values.Values, error) {
+	keys, ok := referencedKeys(tmpl)
+	if !ok {
+		return e.client.GetValues(ctx, e.option.IgnoreNotFound)
+	}
+
+	return provider.GetValuesByKeys(ctx, e.client, keys, e.option.IgnoreNotFound)
+}
+
+func (e engine) execute(tmpl *template.Template, dest io.Writer, data any) error {
 	tmp := &bytes.Buffer{}
-	if err := e.template.Execute(tmp, data); err != nil {
+	if err := tmpl.Execute(tmp, data); err != nil {
 		return fmt.Errorf("%w: %w", ErrRendering, err)
 	}
 
@@ -19,7 +19,12 @@ package engine
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
 	"text/template"
 
@@ -27,6 +32,9 @@ import (
 	"github.com/dwango/yashiro/internal/values"
 	"github.com/dwango/yashiro/pkg/config"
 	"github.com/dwango/yashiro/pkg/engine/encoding"
+
+	// Tests create engines with local files.
+	_ "github.com/dwango/yashiro/pkg/provider/file"
 )
 
 func TestNew(t *testing.T) {
@@ -56,6 +64,149 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNew_independentOptions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "values.json"), []byte(`{"key":"value"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		File: &config.FileConfig{
+			Values:  []config.FileValueConfig{{Path: "values.json"}},
+			BaseDir: dir,
+		},
+	}
+
+	e1, err := New(cfg, IgnoreNotFound(true), TextType(TextTypeYAML))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	e2, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if got, want := e1.(*engine).option, (&opts{IgnoreNotFound: true, TextType: TextTypeYAML}); !reflect.DeepEqual(got, want) {
+		t.Errorf("New() option = %v, want %v", got, want)
+	}
+	if got, want := e2.(*engine).option, defaultOpts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("New() option = %v, want %v", got, want)
+	}
+
+	dest := &bytes.Buffer{}
+	if err := e2.Render(context.Background(), "key: {{ .key }}\nfoo", dest); err != nil {
+		t.Fatalf("engine.Render() error = %v", err)
+	}
+	if gotDest, wantDest := dest.String(), "key: value\nfoo"; gotDest != wantDest {
+		t.Errorf("engine.Render() = %v, want %v", gotDest, wantDest)
+	}
+}
+
+func TestNew_dynamicLookup(t *testing.T) {
+	cfg := &config.Config{
+		File: &config.FileConfig{BaseDir: t.TempDir()},
+	}
+
+	if _, err := New(cfg, DynamicLookup(true)); !errors.Is(err, ErrLookupNotSupported) {
+		t.Errorf("New() error = %v, want %v", err, ErrLookupNotSupported)
+	}
+}
+
+func TestNew_helpers(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "values.json"), []byte(`{"name":"app"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		File: &config.FileConfig{
+			Values:  []config.FileValueConfig{{Path: "values.json"}},
+			BaseDir: dir,
+		},
+	}
+
+	e, err := New(cfg,
+		Helper("_labels.tpl", `{{ define "labels" }}app: {{ .name }}{{ end }}`),
+		Helper("_names.tpl", `{{ define "fullname" }}{{ .name }}-{{ include "suffix" . }}{{ end }}{{ define "suffix" }}prod{{ end }}`),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	dest := &bytes.Buffer{}
+	if err := e.Render(context.Background(), `{{ template "fullname" . }}:{{ include "labels" . | nindent 2 }}`, dest); err != nil {
+		t.Fatalf("engine.Render() error = %v", err)
+	}
+	if gotDest, wantDest := dest.String(), "app-prod:\n  app: app"; gotDest != wantDest {
+		t.Errorf("engine.Render() = %v, want %v", gotDest, wantDest)
+	}
+
+	if _, err := New(cfg, Helper("_invalid.tpl", `{{ define "invalid" }}`)); !errors.Is(err, ErrRendering) {
+		t.Errorf("New() error = %v, want %v", err, ErrRendering)
+	}
+
+	_, err = New(cfg,
+		Helper("base/_helpers.tpl", `{{ define "labels" }}app: {{ .name }}{{ end }}`),
+		Helper("overlays/prod/_helpers.tpl", `{{ define "labels" }}env: prod{{ end }}`),
+	)
+	if !errors.Is(err, ErrHelperConflict) {
+		t.Errorf("New() error = %v, want %v", err, ErrHelperConflict)
+	}
+
+	// A helper in a later scope overrides the one in an earlier scope.
+	e, err = New(cfg,
+		Helper("_helpers.tpl", `{{ define "labels" }}app: {{ .name }}{{ end }}{{ define "env" }}dev{{ end }}`),
+		HelperScope(),
+		Helper("overlays/prod/_helpers.tpl", `{{ define "env" }}prod{{ end }}`),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	dest.Reset()
+	if err := e.Render(context.Background(), `{{ template "labels" . }}, env: {{ template "env" }}`, dest); err != nil {
+		t.Fatalf("engine.Render() error = %v", err)
+	}
+	if gotDest, wantDest := dest.String(), "app: app, env: prod"; gotDest != wantDest {
+		t.Errorf("engine.Render() = %v, want %v", gotDest, wantDest)
+	}
+}
+
+func TestWith(t *testing.T) {
+	cfg := &config.Config{
+		File: &config.FileConfig{BaseDir: t.TempDir()},
+	}
+	base, err := New(cfg, Helper("_helpers.tpl", `{{ define "env" }}dev{{ end }}`))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// Sibling engines define the same template in their own scopes.
+	for _, env := range []string{"stg", "prod"} {
+		e, err := With(base, HelperScope(), Helper(env+"/_helpers.tpl", `{{ define "env" }}`+env+`{{ end }}`))
+		if err != nil {
+			t.Fatalf("With() error = %v", err)
+		}
+		dest := &bytes.Buffer{}
+		if err := e.Render(context.Background(), `{{ template "env" }}`, dest); err != nil {
+			t.Fatalf("engine.Render() error = %v", err)
+		}
+		if gotDest := dest.String(); gotDest != env {
+			t.Errorf("engine.Render() = %v, want %v", gotDest, env)
+		}
+	}
+
+	// The base engine is not changed.
+	dest := &bytes.Buffer{}
+	if err := base.Render(context.Background(), `{{ template "env" }}`, dest); err != nil {
+		t.Fatalf("engine.Render() error = %v", err)
+	}
+	if gotDest, wantDest := dest.String(), "dev"; gotDest != wantDest {
+		t.Errorf("engine.Render() = %v, want %v", gotDest, wantDest)
+	}
+
+	if _, err := With(base, Helper("_conflict.tpl", `{{ define "env" }}prod{{ end }}`)); !errors.Is(err, ErrHelperConflict) {
+		t.Errorf("With() error = %v, want %v", err, ErrHelperConflict)
+	}
+}
+
 type mockClient func(ctx context.Context, ignoreNotFound bool) (values.Values, error)
 
 func (m mockClient) GetValues(ctx context.Context, ignoreNotFound bool) (values.Values, error) {
@@ -235,11 +386,164 @@ func Test_engine_Render(t *testing.T) {
 	}
 }
 
-func Test_engine_render(t *testing.T) {
+func Test_engine_Render_isolated(t *testing.T) {
+	e := engine{
+		client: mockClient(func(ctx context.Context, ignoreNotFound bool) (values.Values, error) {
+			return map[string]any{"key": "value"}, nil
+		}),
+		encodeAndDecoder: &noOpEncodeAndDecoder{},
+		template:         template.New("test").Option("missingkey=error"),
+		option:           &opts{},
+	}
+
+	dest := &bytes.Buffer{}
+	if err := e.Render(context.Background(), `{{ define "helper" }}{{ .key }}{{ end }}{{ template "helper" . }}`, dest); err != nil {
+		t.Fatalf("engine.Render() error = %v", err)
+	}
+	if gotDest := dest.String(); gotDest != "value" {
+		t.Errorf("engine.Render() = %v, want %v", gotDest, "value")
+	}
+
+	// "helper" defined in the previous render is not available.
+	if err := e.Render(context.Background(), `{{ template "helper" . }}`, &bytes.Buffer{}); err == nil {
+		t.Errorf("engine.Render() error = nil, want error")
+	}
+}
+
+func Test_engine_Render_concurrent(t *testing.T) {
+	e := engine{
+		client: mockClient(func(ctx context.Context, ignoreNotFound bool) (values.Values, error) {
+			return map[string]any{"key": "value"}, nil
+		}),
+		encodeAndDecoder: &noOpEncodeAndDecoder{},
+		template:         template.New("test").Option("missingkey=error").Funcs(funcMap()),
+		option:           &opts{},
+	}
+
+	const n = 50
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	dests := make([]*bytes.Buffer, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// each render defines the same template name with a different body
+			text := fmt.Sprintf(`{{ define "helper" }}%d{{ end }}{{ template "helper" . }}-{{ .key | upper }}`, i)
+			dests[i] = &bytes.Buffer{}
+			errs[i] = e.Render(context.Background(), text, dests[i])
+		}()
+	}
+	wg.Wait()
+
+	for i := range n {
+		if errs[i] != nil {
+			t.Errorf("engine.Render() error = %v", errs[i])
+			continue
+		}
+		if gotDest, wantDest := dests[i].String(), fmt.Sprintf("%d-VALUE", i); gotDest != wantDest {
+			t.Errorf("engine.Render() = %v, want %v", gotDest, wantDest)
+		}
+	}
+}
+
+type mockSelectiveClient struct {
+	mockClient
+	getValuesByKeys func(ctx context.Context, keys []string, ignoreNotFound bool) (values.Values, error)
+}
+
+func (m mockSelectiveClient) GetValuesByKeys(ctx context.Context, keys []string, ignoreNotFound bool) (values.Values, error) {
+	return m.getValuesByKeys(ctx, keys, ignoreNotFound)
+}
+
+func Test_engine_Render_selective(t *testing.T) {
+	allValues := mockClient(func(ctx context.Context, ignoreNotFound bool) (values.Values, error) {
+		return map[string]any{"key1": "value1", "key2": "value2", "/key3": "value3"}, nil
+	})
+
+	tests := []struct {
+		name     string
+		text     string
+		wantKeys []string // nil if all values are got
+		wantDest string
+	}{
+		{
+			name:     "ok: referenced keys",
+			text:     `{{ .key1 }}-{{ index . "/key3" }}`,
+			wantKeys: []string{"/key3", "key1"},
+			wantDest: "value1-value3",
+		},
+		{
+			name:     "ok: dynamic access",
+			text:     `{{ range $k, $v := . }}{{ $k }}={{ $v }},{{ end }}`,
+			wantDest: "/key3=value3,key1=value1,key2=value2,",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotKeys []string
+			e := engine{
+				client: mockSelectiveClient{
+					mockClient: allValues,
+					getValuesByKeys: func(ctx context.Context, keys []string, ignoreNotFound bool) (values.Values, error) {
+						gotKeys = keys
+						return allValues(ctx, ignoreNotFound)
+					},
+				},
+				encodeAndDecoder: &noOpEncodeAndDecoder{},
+				template:         template.New("test").Option("missingkey=error"),
+				option:           &opts{},
+			}
+
+			dest := &bytes.Buffer{}
+			if err := e.Render(context.Background(), tt.text, dest); err != nil {
+				t.Fatalf("engine.Render() error = %v", err)
+			}
+			if !reflect.DeepEqual(gotKeys, tt.wantKeys) {
+				t.Errorf("engine.Render() got keys = %v, want %v", gotKeys, tt.wantKeys)
+			}
+			if gotDest := dest.String(); gotDest != tt.wantDest {
+				t.Errorf("engine.Render() = %v, want %v", gotDest, tt.wantDest)
+			}
+		})
+	}
+}
+
+func Test_engine_Render_dynamicLookup(t *testing.T) {
+	e := engine{
+		client: mockClient(func(ctx context.Context, ignoreNotFound bool) (values.Values, error) {
+			return map[string]any{"env": "prod"}, nil
+		}),
+		encodeAndDecoder: &noOpEncodeAndDecoder{},
+		option:           &opts{DynamicLookup: true},
+		lookuper: mockLookuper{
+			parameters: map[string]string{"/app/prod/a": "1", "/app/prod/b": "2"},
+		},
+	}
+	e.template = template.New("test").Option("missingkey=error").Funcs(funcMap()).
+		Funcs(lookupFuncMap(context.Background(), e.lookuper, false))
+
+	dest := &bytes.Buffer{}
+	text := `{{ range list "a" "b" }}{{ ssm (printf "/app/%s/%s" $.env .) }}{{ end }}`
+	if err := e.Render(context.Background(), text, dest); err != nil {
+		t.Fatalf("engine.Render() error = %v", err)
+	}
+	if gotDest, wantDest := dest.String(), "12"; gotDest != wantDest {
+		t.Errorf("engine.Render() = %v, want %v", gotDest, wantDest)
+	}
+
+	// Functions are not defined without dynamic lookup.
+	e.lookuper = nil
+	e.template = template.New("test").Funcs(funcMap())
+	if err := e.Render(context.Background(), text, &bytes.Buffer{}); err == nil {
+		t.Errorf("engine.Render() error = nil, want error")
+	}
+}
+
+func Test_engine_execute(t *testing.T) {
 	type fields struct {
-		client   client.Client
-		template *template.Template
-		option   *opts
+		encodeAndDecoder encoding.EncodeAndDecoder
+		template         *template.Template
 	}
 	type args struct {
 		text string
@@ -252,22 +556,48 @@ func Test_engine_render(t *testing.T) {
 		wantDest string
 		wantErr  bool
 	}{
-		// TODO: Add test cases.
+		{
+			name: "ok",
+			fields: fields{
+				encodeAndDecoder: &noOpEncodeAndDecoder{},
+				template:         template.New("test").Option("missingkey=error"),
+			},
+			args: args{
+				text: "{{ .key }}",
+				data: map[string]any{"key": "value"},
+			},
+			wantDest: "value",
+		},
+		{
+			name: "error: missing key",
+			fields: fields{
+				encodeAndDecoder: &noOpEncodeAndDecoder{},
+				template:         template.New("test").Option("missingkey=error"),
+			},
+			args: args{
+				text: "{{ .notFound }}",
+				data: map[string]any{"key": "value"},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			e := engine{
-				client:   tt.fields.client,
-				template: tt.fields.template,
-				option:   tt.fields.option,
+				encodeAndDecoder: tt.fields.encodeAndDecoder,
+				template:         tt.fields.template,
+			}
+			tmpl, err := e.parse(tt.args.text)
+			if err != nil {
+				t.Fatalf("engine.parse() error = %v", err)
 			}
 			dest := &bytes.Buffer{}
-			if err := e.render(tt.args.text, dest, tt.args.data); (err != nil) != tt.wantErr {
-				t.Errorf("engine.render() error = %v, wantErr %v", err, tt.wantErr)
+			if err := e.execute(tmpl, dest, tt.args.data); (err != nil) != tt.wantErr {
+				t.Errorf("engine.execute() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if gotDest := dest.String(); gotDest != tt.wantDest {
-				t.Errorf("engine.render() = %v, want %v", gotDest, tt.wantDest)
+				t.Errorf("engine.execute() = %v, want %v", gotDest, tt.wantDest)
 			}
 		})
 	}
@@ -17,11 +17,15 @@
 package engine
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"text/template"
 
 	"github.com/Masterminds/sprig/v3"
+	"github.com/dwango/yashiro/internal/client"
+	"github.com/dwango/yashiro/internal/values"
 	"sigs.k8s.io/yaml"
 )
 
@@ -46,6 +50,102 @@ func funcMap() template.FuncMap {
 	return f
 }
 
+// maxIncludeDepth is the maximum depth of nested include and tpl calls, which stops infinite
+// recursion of templates.
+const maxIncludeDepth = 1000
+
+// templateFuncMap returns functions which render with tmpl: include renders a named template and
+// returns the result, so that it can be piped, e.g. {{ include "labels" . | nindent 4 }}, and tpl
+// renders a string as a template.
+func templateFuncMap(tmpl *template.Template) template.FuncMap {
+	return templateFuncs(tmpl, new(int))
+}
+
+func templateFuncs(tmpl *template.Template, depth *int) template.FuncMap {
+	enter := func(name string) error {
+		if *depth >= maxIncludeDepth {
+			return fmt.Errorf("%w: '%s'", ErrIncludeDepth, name)
+		}
+		*depth++
+		return nil
+	}
+
+	return template.FuncMap{
+		"include": func(name string, data any) (string, error) {
+			if err := enter(name); err != nil {
+				return "", err
+			}
+			defer func() { *depth-- }()
+
+			buf := &strings.Builder{}
+			if err := tmpl.ExecuteTemplate(buf, name, data); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
+		"tpl": func(text string, data any) (string, error) {
+			if err := enter("tpl"); err != nil {
+				return "", err
+			}
+			defer func() { *depth-- }()
+
+			// Parse text on a clone, so that templates defined in text do not overwrite others.
+			t, err := tmpl.Clone()
+			if err != nil {
+				return "", err
+			}
+			t.Funcs(templateFuncs(t, depth))
+			if _, err := t.New("tpl").Parse(text); err != nil {
+				return "", err
+			}
+
+			buf := &strings.Builder{}
+			if err := t.ExecuteTemplate(buf, "tpl", data); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
+	}
+}
+
+// lookupFuncMap returns functions which get values from the external stores at render time. If
+// ignoreNotFound is true, values not found are empty.
+func lookupFuncMap(ctx context.Context, l client.Lookuper, ignoreNotFound bool) template.FuncMap {
+	lookup := func(get func(context.Context, string) (*string, error), name string) (string, error) {
+		value, err := get(ctx, name)
+		if err != nil {
+			return "", err
+		}
+		if value == nil {
+			if ignoreNotFound {
+				return "", nil
+			}
+			return "", fmt.Errorf("%w: name='%s'", ErrLookupNotFound, name)
+		}
+		return *value, nil
+	}
+
+	return template.FuncMap{
+		"ssm": func(name string) (string, error) {
+			return lookup(l.LookupParameter, name)
+		},
+		"secret": func(id string) (string, error) {
+			return lookup(l.LookupSecret, id)
+		},
+		"secretJSON": func(id string) (map[string]any, error) {
+			value, err := lookup(l.LookupSecret, id)
+			if err != nil || len(value) == 0 {
+				return map[string]any{}, err
+			}
+			m := map[string]any{}
+			if err := json.Unmarshal([]byte(value), &m); err != nil {
+				return map[string]any{}, fmt.Errorf("%w: name='%s': %w", values.ErrInvalidJSON, id, err)
+			}
+			return m, nil
+		},
+	}
+}
+
 // toYAML takes an any, marshals it to yaml, and returns a string. It will
 // always return a string, even on marshal error (empty string).
 //
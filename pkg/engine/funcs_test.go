@@ -17,8 +17,12 @@
 package engine
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"reflect"
 	"testing"
+	"text/template"
 )
 
 func Test_funcMap(t *testing.T) {
@@ -67,6 +71,11 @@ func Test_funcMap(t *testing.T) {
 			key:   "fromJsonArray",
 			isNil: false,
 		},
+		{
+			name:  "does not exist ssm function without dynamic lookup",
+			key:   "ssm",
+			isNil: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -77,6 +86,164 @@ func Test_funcMap(t *testing.T) {
 	}
 }
 
+func Test_templateFuncMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		data    any
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "ok: include",
+			text: `{{ define "labels" }}app: {{ .name }}{{ end }}labels:{{ include "labels" . | nindent 2 }}`,
+			data: map[string]any{"name": "yashiro"},
+			want: "labels:\n  app: yashiro",
+		},
+		{
+			name: "ok: tpl",
+			text: `{{ tpl .text . }}`,
+			data: map[string]any{"name": "yashiro", "text": `{{ define "t" }}{{ .name }}{{ end }}{{ include "t" . | upper }}`},
+			want: "YASHIRO",
+		},
+		{
+			name: "ok: tpl does not overwrite templates",
+			text: `{{ define "t" }}outer{{ end }}{{ tpl "{{ define \"t\" }}inner{{ end }}{{ template \"t\" }}" . }}-{{ template "t" }}`,
+			want: "inner-outer",
+		},
+		{
+			name:    "error: include not defined template",
+			text:    `{{ include "notfound" . }}`,
+			wantErr: true,
+		},
+		{
+			name:    "error: infinite recursion",
+			text:    `{{ define "loop" }}{{ include "loop" . }}{{ end }}{{ include "loop" . }}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := template.New("test").Funcs(funcMap())
+			tmpl.Funcs(templateFuncMap(tmpl))
+			tmpl = template.Must(tmpl.Parse(tt.text))
+
+			buf := &bytes.Buffer{}
+			err := tmpl.Execute(buf, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("templateFuncMap() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && buf.String() != tt.want {
+				t.Errorf("templateFuncMap() = %v, want %v", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+// mockLookuper has parameters and secrets keyed by names.
+type mockLookuper struct {
+	parameters map[string]string
+	secrets    map[string]string
+}
+
+func (m mockLookuper) LookupParameter(_ context.Context, name string) (*string, error) {
+	return lookupMock(m.parameters, name)
+}
+
+func (m mockLookuper) LookupSecret(_ context.Context, id string) (*string, error) {
+	return lookupMock(m.secrets, id)
+}
+
+func lookupMock(m map[string]string, name string) (*string, error) {
+	if name == "error" {
+		return nil, errors.New("error")
+	}
+	v, ok := m[name]
+	if !ok {
+		return nil, nil
+	}
+	return &v, nil
+}
+
+func Test_lookupFuncMap(t *testing.T) {
+	l := mockLookuper{
+		parameters: map[string]string{"/app/prod/tag": "v1", "/app/prod/tag:2": "v0"},
+		secrets:    map[string]string{"app/db": `{"user":"admin"}`, "app/token": "token"},
+	}
+
+	type args struct {
+		text           string
+		ignoreNotFound bool
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "ok: ssm",
+			args: args{
+				text: `{{ ssm "/app/prod/tag" }},{{ ssm (printf "%s:%d" "/app/prod/tag" 2) }}`,
+			},
+			want: "v1,v0",
+		},
+		{
+			name: "ok: secret and secretJSON",
+			args: args{
+				text: `{{ secret "app/token" }},{{ (secretJSON "app/db").user }}`,
+			},
+			want: "token,admin",
+		},
+		{
+			name: "ok: ignore not found",
+			args: args{
+				text:           `[{{ ssm "/notfound" }}][{{ secret "notfound" }}][{{ len (secretJSON "notfound") }}]`,
+				ignoreNotFound: true,
+			},
+			want: "[][][0]",
+		},
+		{
+			name: "error: not found",
+			args: args{
+				text: `{{ ssm "/notfound" }}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: lookup error",
+			args: args{
+				text:           `{{ secret "error" }}`,
+				ignoreNotFound: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: invalid json",
+			args: args{
+				text: `{{ secretJSON "app/token" }}`,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := template.Must(template.New("test").Funcs(lookupFuncMap(context.Background(), l, tt.args.ignoreNotFound)).Parse(tt.args.text))
+
+			buf := &bytes.Buffer{}
+			err := tmpl.Execute(buf, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("lookupFuncMap() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && buf.String() != tt.want {
+				t.Errorf("lookupFuncMap() = %v, want %v", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
 func Test_toYAML(t *testing.T) {
 	type args struct {
 		v any
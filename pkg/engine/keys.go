@@ -0,0 +1,193 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package engine
+
+import (
+	"slices"
+	"text/template"
+	"text/template/parse"
+)
+
+// referencedKeys returns top-level keys of values which tmpl references, e.g. "key" of
+// {{ .key.child }}, {{ $.key }} and {{ index . "key" }}. If values are accessed dynamically,
+// e.g. {{ toJson . }} or {{ index . $name }}, keys cannot be determined and it returns false.
+func referencedKeys(tmpl *template.Template) ([]string, bool) {
+	if tmpl.Tree == nil || tmpl.Root == nil {
+		return nil, true
+	}
+
+	w := &keysWalker{
+		tmpl:    tmpl,
+		keys:    make(map[string]struct{}),
+		visited: make(map[string]bool),
+	}
+	w.walkTemplate(tmpl.Name())
+	if w.dynamic {
+		return nil, false
+	}
+
+	keys := make([]string, 0, len(w.keys))
+	for k := range w.keys {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	return keys, true
+}
+
+// keysWalker walks parse trees and collects top-level keys of values.
+type keysWalker struct {
+	tmpl    *template.Template
+	keys    map[string]struct{}
+	visited map[string]bool // templates already walked with values as dot
+	dynamic bool
+}
+
+// walkScope is what dot and $ are while walking nodes.
+type walkScope struct {
+	dotIsRoot    bool // dot is values
+	dollarIsRoot bool // $ is values
+}
+
+// walkTemplate walks the named template executed with values as dot.
+func (w *keysWalker) walkTemplate(name string) {
+	if w.visited[name] {
+		return
+	}
+	w.visited[name] = true
+
+	t := w.tmpl.Lookup(name)
+	if t == nil || t.Tree == nil || t.Root == nil {
+		return
+	}
+	w.walk(t.Root, walkScope{dotIsRoot: true, dollarIsRoot: true})
+}
+
+func (w *keysWalker) walk(node parse.Node, scope walkScope) {
+	if w.dynamic || node == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			w.walk(child, scope)
+		}
+	case *parse.ActionNode:
+		w.walk(n.Pipe, scope)
+	case *parse.IfNode:
+		w.walk(n.Pipe, scope)
+		w.walk(n.List, scope)
+		w.walk(n.ElseList, scope)
+	case *parse.WithNode:
+		w.walk(n.Pipe, scope)
+		w.walk(n.List, walkScope{dollarIsRoot: scope.dollarIsRoot})
+		w.walk(n.ElseList, scope)
+	case *parse.RangeNode:
+		w.walk(n.Pipe, scope)
+		w.walk(n.List, walkScope{dollarIsRoot: scope.dollarIsRoot})
+		w.walk(n.ElseList, scope)
+	case *parse.TemplateNode:
+		if w.isRoot(n.Pipe, scope) {
+			w.walkTemplate(n.Name)
+			return
+		}
+		// Values are not passed to the template, so keys are only referenced by the pipeline.
+		w.walk(n.Pipe, scope)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			w.walkCommand(cmd, scope)
+		}
+	case *parse.FieldNode:
+		if scope.dotIsRoot {
+			w.keys[n.Ident[0]] = struct{}{}
+		}
+	case *parse.VariableNode:
+		if n.Ident[0] != "$" || !scope.dollarIsRoot {
+			return
+		}
+		if len(n.Ident) < 2 {
+			w.dynamic = true
+			return
+		}
+		w.keys[n.Ident[1]] = struct{}{}
+	case *parse.DotNode:
+		if scope.dotIsRoot {
+			w.dynamic = true
+		}
+	case *parse.ChainNode:
+		w.walk(n.Node, scope)
+	}
+}
+
+func (w *keysWalker) walkCommand(cmd *parse.CommandNode, scope walkScope) {
+	args := cmd.Args
+	// {{ include "name" . }} references keys like {{ template "name" . }}.
+	if len(args) >= 3 && isIdentifier(args[0], "include") && w.isRootArg(args[2], scope) {
+		name, ok := args[1].(*parse.StringNode)
+		if !ok {
+			w.dynamic = true
+			return
+		}
+		w.walkTemplate(name.Text)
+		args = args[3:]
+	}
+	// {{ index . "key" }} references only "key" of values.
+	if len(args) >= 3 && isIdentifier(args[0], "index") && w.isRootArg(args[1], scope) {
+		key, ok := args[2].(*parse.StringNode)
+		if !ok {
+			w.dynamic = true
+			return
+		}
+		w.keys[key.Text] = struct{}{}
+		args = args[3:]
+	}
+
+	for _, arg := range args {
+		w.walk(arg, scope)
+	}
+}
+
+// isRoot reports whether the pipeline is just values, e.g. {{ template "name" . }}.
+func (w *keysWalker) isRoot(pipe *parse.PipeNode, scope walkScope) bool {
+	if pipe == nil || len(pipe.Decl) != 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+
+	return w.isRootArg(pipe.Cmds[0].Args[0], scope)
+}
+
+func (w *keysWalker) isRootArg(arg parse.Node, scope walkScope) bool {
+	switch n := arg.(type) {
+	case *parse.DotNode:
+		return scope.dotIsRoot
+	case *parse.VariableNode:
+		return len(n.Ident) == 1 && n.Ident[0] == "$" && scope.dollarIsRoot
+	default:
+		return false
+	}
+}
+
+func isIdentifier(node parse.Node, name string) bool {
+	n, ok := node.(*parse.IdentifierNode)
+	return ok && n.Ident == name
+}
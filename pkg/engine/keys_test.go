@@ -0,0 +1,108 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+func Test_referencedKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		want   []string
+		wantOk bool
+	}{
+		{
+			name:   "ok: fields",
+			text:   `{{ .key1.child }} {{ .key2 | upper }} {{ if .key3 }}{{ .key4 }}{{ else }}{{ .key5 }}{{ end }}`,
+			want:   []string{"key1", "key2", "key3", "key4", "key5"},
+			wantOk: true,
+		},
+		{
+			name:   "ok: index with constant key",
+			text:   `{{ index . "/app/key1" }} {{ index $ "key2" "child" }}`,
+			want:   []string{"/app/key1", "key2"},
+			wantOk: true,
+		},
+		{
+			name:   "ok: dot is changed by with and range",
+			text:   `{{ with .key1 }}{{ .child }}{{ $.key2 }}{{ else }}{{ .key3 }}{{ end }}{{ range .key4 }}{{ . }}{{ end }}`,
+			want:   []string{"key1", "key2", "key3", "key4"},
+			wantOk: true,
+		},
+		{
+			name:   "ok: templates",
+			text:   `{{ define "t1" }}{{ .key1 }}{{ template "t1" . }}{{ end }}{{ define "t2" }}{{ .child }}{{ end }}{{ define "unused" }}{{ .key3 }}{{ end }}{{ template "t1" . }}{{ template "t2" .key2 }}`,
+			want:   []string{"key1", "key2"},
+			wantOk: true,
+		},
+		{
+			name:   "ok: include",
+			text:   `{{ define "t1" }}{{ .key1 }}{{ end }}{{ include "t1" . | nindent 2 }}{{ include "t1" .key2 }}`,
+			want:   []string{"key1", "key2"},
+			wantOk: true,
+		},
+		{
+			name:   "ok: no references",
+			text:   `text`,
+			want:   []string{},
+			wantOk: true,
+		},
+		{
+			name:   "dynamic: dot as an argument",
+			text:   `{{ .key1 }}{{ toJson . }}`,
+			wantOk: false,
+		},
+		{
+			name:   "dynamic: index with variable key",
+			text:   `{{ $k := "key1" }}{{ index . $k }}`,
+			wantOk: false,
+		},
+		{
+			name:   "dynamic: range values",
+			text:   `{{ range $k, $v := $ }}{{ $v }}{{ end }}`,
+			wantOk: false,
+		},
+		{
+			name:   "dynamic: tpl",
+			text:   `{{ tpl .key1 . }}`,
+			wantOk: false,
+		},
+		{
+			name:   "dynamic: in a template",
+			text:   `{{ define "t1" }}{{ toJson . }}{{ end }}{{ template "t1" . }}`,
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := template.New("test").Funcs(funcMap())
+			tmpl = template.Must(tmpl.Funcs(templateFuncMap(tmpl)).Parse(tt.text))
+
+			got, gotOk := referencedKeys(tmpl)
+			if gotOk != tt.wantOk {
+				t.Errorf("referencedKeys() ok = %v, want %v", gotOk, tt.wantOk)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("referencedKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -46,12 +46,53 @@ func IgnoreNotFound(b bool) Option {
 	}
 }
 
+// DynamicLookup enables template functions which get values not declared in the configuration
+// from the external stores at render time: ssm, secret and secretJSON.
+func DynamicLookup(b bool) Option {
+	return func(o *opts) {
+		o.DynamicLookup = b
+	}
+}
+
+// Helper adds a helper template, e.g. the content of "_helpers.tpl". Templates defined in the
+// helper can be used by template and include in every render. A template must not be defined in
+// more than one helper of the same scope.
+func Helper(name, text string) Option {
+	return func(o *opts) {
+		o.Helpers = append(o.Helpers, helper{name: name, text: text, scope: o.HelperScope})
+	}
+}
+
+// HelperScope starts a new scope of helpers. Templates defined in helpers added after it replace
+// the ones with the same names in earlier scopes, e.g. helpers in a directory override helpers in
+// its parent directories.
+func HelperScope() Option {
+	return func(o *opts) {
+		o.HelperScope++
+	}
+}
+
+type helper struct {
+	name  string
+	text  string
+	scope int
+}
+
 type opts struct {
 	IgnoreNotFound bool
 	TextType       TextTypeOpt
+	DynamicLookup  bool
+	Helpers        []helper
+	HelperScope    int
 }
 
-var defaultOpts = &opts{
-	IgnoreNotFound: false,
-	TextType:       TextTypePlain,
+// defaultOpts returns fresh options, since Option funcs modify them in place.
+func defaultOpts() *opts {
+	return &opts{
+		IgnoreNotFound: false,
+		TextType:       TextTypePlain,
+		DynamicLookup:  false,
+		Helpers:        nil,
+		HelperScope:    0,
+	}
 }
@@ -0,0 +1,158 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package azure provides values from Azure Key Vault secrets. It registers the "azure" provider
+// when it is imported:
+//
+//	import _ "github.com/dwango/yashiro/pkg/provider/azure"
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+	"github.com/dwango/yashiro/internal/client"
+	"github.com/dwango/yashiro/internal/client/cache"
+	"github.com/dwango/yashiro/internal/values"
+	"github.com/dwango/yashiro/pkg/config"
+	"github.com/dwango/yashiro/pkg/provider"
+)
+
+func init() {
+	provider.Register("azure", newAzureClient)
+}
+
+type azureClient struct {
+	// secretsClients are clients for each vault URL.
+	secretsClients map[string]azureSecretsClient
+	keyVaultValue  []config.AzureKeyVaultValueConfig
+}
+
+func newAzureClient(cfg *config.Config) (provider.Provider, error) {
+	if cfg.Azure == nil {
+		return nil, nil
+	}
+
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var cc cache.Cache
+	if cfg.Global.EnableCache {
+		cc, err = cache.New(cfg.Global.Cache, cache.WithCacheKeys("azure"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	values := make([]config.AzureKeyVaultValueConfig, 0, len(cfg.Azure.KeyVaultValues))
+	secretsClients := make(map[string]azureSecretsClient)
+	for _, v := range cfg.Azure.KeyVaultValues {
+		if len(v.VaultURL) == 0 {
+			v.VaultURL = cfg.Azure.VaultURL
+		}
+		if len(v.VaultURL) == 0 {
+			return nil, fmt.Errorf("vault url is required: name='%s'", v.Name)
+		}
+		values = append(values, v)
+
+		if _, ok := secretsClients[v.VaultURL]; ok {
+			continue
+		}
+		cli, err := azsecrets.NewClient(v.VaultURL, cred, nil)
+		if err != nil {
+			return nil, err
+		}
+		secretsClients[v.VaultURL] = &azureSecretsClientWithCache{
+			client:   cli,
+			cache:    cc,
+			vaultURL: v.VaultURL,
+		}
+	}
+
+	return &azureClient{
+		secretsClients: secretsClients,
+		keyVaultValue:  values,
+	}, nil
+}
+
+func (c azureClient) GetValues(ctx context.Context, ignoreNotFound bool) (values.Values, error) {
+	values := make(values.Values, len(c.keyVaultValue))
+
+	for _, v := range c.keyVaultValue {
+		output, err := c.secretsClients[v.VaultURL].GetSecret(ctx, v.Name, v.Version, nil)
+		if err != nil {
+			var respErr *azcore.ResponseError
+			if ignoreNotFound && errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return nil, client.GettingValueError(v.Name, err)
+		}
+
+		if err := values.SetValue(v, output.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// GetValuesByKeys gets only Key Vault secrets referenced by keys.
+func (c azureClient) GetValuesByKeys(ctx context.Context, keys []string, ignoreNotFound bool) (values.Values, error) {
+	c.keyVaultValue = client.SelectValues(c.keyVaultValue, keys, client.ReferenceName)
+
+	return c.GetValues(ctx, ignoreNotFound)
+}
+
+type azureSecretsClient interface {
+	GetSecret(ctx context.Context, name string, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error)
+}
+
+type azureSecretsClientWithCache struct {
+	client   azureSecretsClient
+	cache    cache.Cache
+	vaultURL string
+}
+
+func (c azureSecretsClientWithCache) GetSecret(ctx context.Context, name string, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error) {
+	if c.cache == nil {
+		return c.client.GetSecret(ctx, name, version, options)
+	}
+
+	key := c.vaultURL + "/secrets/" + name
+	if len(version) != 0 {
+		key += "/" + version
+	}
+
+	value, err := client.GetWithCache(ctx, c.cache, key, true, nil, func(ctx context.Context) (*string, error) {
+		output, err := c.client.GetSecret(ctx, name, version, options)
+		if err != nil {
+			return nil, err
+		}
+		return output.Value, nil
+	})
+	if err != nil {
+		return azsecrets.GetSecretResponse{}, err
+	}
+
+	return azsecrets.GetSecretResponse{Secret: azsecrets.Secret{Value: value}}, nil
+}
@@ -0,0 +1,263 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+	"github.com/dwango/yashiro/internal/client/cache"
+	"github.com/dwango/yashiro/internal/values"
+	"github.com/dwango/yashiro/pkg/config"
+	"github.com/dwango/yashiro/pkg/provider"
+)
+
+func Test_newAzureClient(t *testing.T) {
+	type args struct {
+		cfg *config.Config
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    provider.Provider
+		wantErr bool
+	}{
+		{
+			name: "ok: azure is not configured",
+			args: args{
+				cfg: &config.Config{},
+			},
+			want: nil,
+		},
+		{
+			name: "error: vault url is empty",
+			args: args{
+				cfg: &config.Config{Azure: &config.AzureConfig{
+					KeyVaultValues: []config.AzureKeyVaultValueConfig{
+						{ValueConfig: config.ValueConfig{Name: "secret"}},
+					},
+				}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newAzureClient(tt.args.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newAzureClient() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newAzureClient() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type mockAzureSecretsClient func(ctx context.Context, name string, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error)
+
+func (m mockAzureSecretsClient) GetSecret(ctx context.Context, name string, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error) {
+	return m(ctx, name, version, options)
+}
+
+var textStrAzureSecretsClient = mockAzureSecretsClient(func(ctx context.Context, name string, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error) {
+	return azsecrets.GetSecretResponse{Secret: azsecrets.Secret{Value: stringPtr("test")}}, nil
+})
+
+func Test_azureClient_GetValues(t *testing.T) {
+	type fields struct {
+		secretsClients map[string]azureSecretsClient
+		keyVaultValue  []config.AzureKeyVaultValueConfig
+	}
+	type args struct {
+		ctx            context.Context
+		ignoreNotFound bool
+	}
+
+	notFoundClient := mockAzureSecretsClient(func(ctx context.Context, name string, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error) {
+		return azsecrets.GetSecretResponse{}, &azcore.ResponseError{StatusCode: http.StatusNotFound, ErrorCode: "SecretNotFound"}
+	})
+	jsonClient := mockAzureSecretsClient(func(ctx context.Context, name string, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error) {
+		return azsecrets.GetSecretResponse{Secret: azsecrets.Secret{Value: stringPtr(`{"key":"value"}`)}}, nil
+	})
+
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    values.Values
+		wantErr bool
+	}{
+		{
+			name: "ok: multiple vaults",
+			fields: fields{
+				secretsClients: map[string]azureSecretsClient{
+					"https://vault1.vault.azure.net": textStrAzureSecretsClient,
+					"https://vault2.vault.azure.net": jsonClient,
+				},
+				keyVaultValue: []config.AzureKeyVaultValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "text"}, VaultURL: "https://vault1.vault.azure.net"},
+					{ValueConfig: config.ValueConfig{Name: "json", IsJSON: true}, VaultURL: "https://vault2.vault.azure.net"},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			want: values.Values{"text": "test", "json": map[string]any{"key": "value"}},
+		},
+		{
+			name: "ok: ignore not found error",
+			fields: fields{
+				secretsClients: map[string]azureSecretsClient{
+					"https://vault1.vault.azure.net": notFoundClient,
+				},
+				keyVaultValue: []config.AzureKeyVaultValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "text"}, VaultURL: "https://vault1.vault.azure.net"},
+				},
+			},
+			args: args{
+				ctx:            context.Background(),
+				ignoreNotFound: true,
+			},
+			want: values.Values{},
+		},
+		{
+			name: "error: return not found",
+			fields: fields{
+				secretsClients: map[string]azureSecretsClient{
+					"https://vault1.vault.azure.net": notFoundClient,
+				},
+				keyVaultValue: []config.AzureKeyVaultValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "text"}, VaultURL: "https://vault1.vault.azure.net"},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := azureClient{
+				secretsClients: tt.fields.secretsClients,
+				keyVaultValue:  tt.fields.keyVaultValue,
+			}
+			got, err := c.GetValues(tt.args.ctx, tt.args.ignoreNotFound)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("azureClient.GetValues() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("azureClient.GetValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_azureSecretsClientWithCache_GetSecret(t *testing.T) {
+	type fields struct {
+		client azureSecretsClient
+		cache  cache.Cache
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		want    azsecrets.GetSecretResponse
+		wantErr bool
+	}{
+		{
+			name: "ok: get from cache",
+			fields: fields{
+				client: nil,
+				cache:  mockCache{load: mockLoadFunc, save: mockSaveFunc},
+			},
+			want: azsecrets.GetSecretResponse{Secret: azsecrets.Secret{Value: stringPtr("value")}},
+		},
+		{
+			name: "ok: get from client(cache disabled)",
+			fields: fields{
+				client: textStrAzureSecretsClient,
+				cache:  nil,
+			},
+			want: azsecrets.GetSecretResponse{Secret: azsecrets.Secret{Value: stringPtr("test")}},
+		},
+		{
+			name: "ok: get from client(cache expired)",
+			fields: fields{
+				client: textStrAzureSecretsClient,
+				cache:  mockCache{load: mockLoadFuncExpired, save: mockSaveFunc},
+			},
+			want: azsecrets.GetSecretResponse{Secret: azsecrets.Secret{Value: stringPtr("test")}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := azureSecretsClientWithCache{
+				client:   tt.fields.client,
+				cache:    tt.fields.cache,
+				vaultURL: "https://vault.vault.azure.net",
+			}
+			got, err := c.GetSecret(context.Background(), "any", "", nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("azureSecretsClientWithCache.GetSecret() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("azureSecretsClientWithCache.GetSecret() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+var (
+	mockLoadFunc = func(_ context.Context, key string, decrypt bool) (*string, bool, error) {
+		return stringPtr("value"), false, nil
+	}
+	mockLoadFuncExpired = func(_ context.Context, key string, decrypt bool) (*string, bool, error) {
+		return stringPtr("value"), true, nil
+	}
+	mockSaveFunc = func(_ context.Context, key string, value *string, encrypt bool) error {
+		return nil
+	}
+)
+
+type mockCache struct {
+	load func(ctx context.Context, key string, decrypt bool) (*string, bool, error)
+	save func(ctx context.Context, key string, value *string, encrypt bool) error
+}
+
+func (m mockCache) Load(ctx context.Context, key string, decrypt bool) (*string, bool, error) {
+	return m.load(ctx, key, decrypt)
+}
+
+func (m mockCache) LoadStale(_ context.Context, _ string, _ bool) (*string, error) {
+	return nil, nil
+}
+
+func (m mockCache) Save(ctx context.Context, key string, value *string, encrypt bool) error {
+	return m.save(ctx, key, value, encrypt)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,90 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package file provides values from local JSON, YAML and dotenv files. It registers the "file"
+// provider when it is imported:
+//
+//	import _ "github.com/dwango/yashiro/pkg/provider/file"
+package file
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/dwango/yashiro/internal/client"
+	"github.com/dwango/yashiro/internal/localfile"
+	"github.com/dwango/yashiro/internal/values"
+	"github.com/dwango/yashiro/pkg/config"
+	"github.com/dwango/yashiro/pkg/provider"
+)
+
+func init() {
+	provider.Register("file", newFileClient)
+}
+
+type fileClient struct {
+	baseDir   string
+	fileValue []config.FileValueConfig
+}
+
+func newFileClient(cfg *config.Config) (provider.Provider, error) {
+	if cfg.File == nil {
+		return nil, nil
+	}
+
+	return &fileClient{
+		baseDir:   cfg.File.BaseDir,
+		fileValue: cfg.File.Values,
+	}, nil
+}
+
+func (c fileClient) GetValues(_ context.Context, ignoreNotFound bool) (values.Values, error) {
+	values := make(values.Values)
+
+	for _, v := range c.fileValue {
+		path := localfile.ResolvePath(c.baseDir, v.Path)
+		format := v.Format
+		if format == config.FileFormatUnspecified {
+			format = localfile.FormatFromPath(path)
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			if ignoreNotFound && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, client.GettingValueError(v.Path, err)
+		}
+
+		content, err := localfile.Parse(b, format)
+		if err != nil {
+			return nil, client.GettingValueError(v.Path, err)
+		}
+
+		localfile.SetValues(values, v, content)
+	}
+
+	return values, nil
+}
+
+// GetValuesByKeys skips files whose Ref is not in keys. Files without Ref are always read.
+func (c fileClient) GetValuesByKeys(ctx context.Context, keys []string, ignoreNotFound bool) (values.Values, error) {
+	c.fileValue = client.SelectValues(c.fileValue, keys, localfile.ReferenceName)
+
+	return c.GetValues(ctx, ignoreNotFound)
+}
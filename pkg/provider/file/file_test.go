@@ -0,0 +1,147 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/dwango/yashiro/internal/values"
+	"github.com/dwango/yashiro/pkg/config"
+)
+
+func Test_fileClient_GetValues(t *testing.T) {
+	type fields struct {
+		fileValue []config.FileValueConfig
+	}
+	type args struct {
+		ctx            context.Context
+		ignoreNotFound bool
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    values.Values
+		wantErr bool
+	}{
+		{
+			name: "ok: yaml without ref",
+			fields: fields{
+				fileValue: []config.FileValueConfig{
+					{Path: "values.yaml"},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			want: values.Values{
+				"example": map[string]any{
+					"imageTag": "latest",
+					"roleArn":  "arn:aws:iam::012345678901:role/PodRole",
+				},
+				"exampleSecure": "password",
+			},
+		},
+		{
+			name: "ok: json and dotenv with ref",
+			fields: fields{
+				fileValue: []config.FileValueConfig{
+					{Path: "values.json", Ref: stringPtr("json")},
+					{Path: ".env", Ref: stringPtr("env")},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			want: values.Values{
+				"json": map[string]any{"db": map[string]any{"host": "localhost", "port": float64(5432)}},
+				"env":  map[string]any{"DB_USER": "user", "DB_PASSWORD": "pass word"},
+			},
+		},
+		{
+			name: "ok: specify format",
+			fields: fields{
+				fileValue: []config.FileValueConfig{
+					{Path: "values.txt", Format: config.FileFormatDotenv},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			want: values.Values{"key": "value"},
+		},
+		{
+			name: "ok: ignore not found error",
+			fields: fields{
+				fileValue: []config.FileValueConfig{
+					{Path: "notfound.yaml"},
+				},
+			},
+			args: args{
+				ctx:            context.Background(),
+				ignoreNotFound: true,
+			},
+			want: values.Values{},
+		},
+		{
+			name: "error: file not found",
+			fields: fields{
+				fileValue: []config.FileValueConfig{
+					{Path: "notfound.yaml"},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: unknown format",
+			fields: fields{
+				fileValue: []config.FileValueConfig{
+					{Path: "values.txt"},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fileClient{
+				baseDir:   "testdata",
+				fileValue: tt.fields.fileValue,
+			}
+			got, err := c.GetValues(tt.args.ctx, tt.args.ignoreNotFound)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("fileClient.GetValues() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fileClient.GetValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,184 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package gcp provides values from Google Cloud Secret Manager. It registers the "gcp" provider
+// when it is imported:
+//
+//	import _ "github.com/dwango/yashiro/pkg/provider/gcp"
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/dwango/yashiro/internal/client"
+	"github.com/dwango/yashiro/internal/client/cache"
+	"github.com/dwango/yashiro/internal/values"
+	"github.com/dwango/yashiro/pkg/config"
+	"github.com/dwango/yashiro/pkg/provider"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	provider.Register("gcp", newGcpClient)
+}
+
+type gcpClient struct {
+	smClient           gcpSecretManagerClient
+	project            string
+	secretManagerValue []config.GcpSecretManagerValueConfig
+}
+
+func newGcpClient(cfg *config.Config) (provider.Provider, error) {
+	if cfg.Gcp == nil {
+		return nil, nil
+	}
+
+	var opts []option.ClientOption
+	if len(cfg.Gcp.Endpoint) != 0 {
+		opts = append(opts, option.WithEndpoint(cfg.Gcp.Endpoint))
+	}
+	if cfg.Gcp.Insecure {
+		opts = append(opts,
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		)
+	}
+
+	// The client connects lazily, so it is not closed until the process exits.
+	smClient, err := secretmanager.NewClient(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var cc cache.Cache
+	if cfg.Global.EnableCache {
+		cc, err = cache.New(cfg.Global.Cache, cache.WithCacheKeys("gcp", cfg.Gcp.Endpoint))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &gcpClient{
+		smClient: &gcpSecretManagerClientWithCache{
+			client: smClient,
+			cache:  cc,
+		},
+		project:            cfg.Gcp.Project,
+		secretManagerValue: cfg.Gcp.SecretManagerValues,
+	}, nil
+}
+
+func (c gcpClient) GetValues(ctx context.Context, ignoreNotFound bool) (values.Values, error) {
+	values := make(values.Values, len(c.secretManagerValue))
+
+	for _, v := range c.secretManagerValue {
+		name, err := gcpSecretVersionName(c.project, v)
+		if err != nil {
+			return nil, client.GettingValueError(v.Name, err)
+		}
+
+		output, err := c.smClient.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{
+			Name: name,
+		})
+		if err != nil {
+			if ignoreNotFound && status.Code(err) == codes.NotFound {
+				continue
+			}
+			return nil, client.GettingValueError(v.Name, err)
+		}
+
+		value := string(output.GetPayload().GetData())
+		if err := values.SetValue(v, &value); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// GetValuesByKeys accesses only secret versions referenced by keys.
+func (c gcpClient) GetValuesByKeys(ctx context.Context, keys []string, ignoreNotFound bool) (values.Values, error) {
+	c.secretManagerValue = client.SelectValues(c.secretManagerValue, keys, client.ReferenceName)
+
+	return c.GetValues(ctx, ignoreNotFound)
+}
+
+// gcpSecretVersionName returns the resource name formatted as "projects/*/secrets/*/versions/*".
+func gcpSecretVersionName(project string, v config.GcpSecretManagerValueConfig) (string, error) {
+	version := v.Version
+	if len(version) == 0 {
+		version = config.DefaultGcpSecretVersion
+	}
+
+	parts := strings.Split(v.Name, "/")
+	switch {
+	case len(parts) == 6 && parts[0] == "projects" && parts[2] == "secrets" && parts[4] == "versions":
+		if len(v.Version) != 0 && v.Version != parts[5] {
+			return "", fmt.Errorf("version is specified in both name and version")
+		}
+		return v.Name, nil
+	case len(parts) == 4 && parts[0] == "projects" && parts[2] == "secrets":
+		return v.Name + "/versions/" + version, nil
+	case len(parts) == 1:
+		if len(project) == 0 {
+			return "", fmt.Errorf("project is required for secret id")
+		}
+		return fmt.Sprintf("projects/%s/secrets/%s/versions/%s", project, v.Name, version), nil
+	default:
+		return "", fmt.Errorf("invalid secret name")
+	}
+}
+
+type gcpSecretManagerClient interface {
+	AccessSecretVersion(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest, opts ...gax.CallOption) (*secretmanagerpb.AccessSecretVersionResponse, error)
+}
+
+type gcpSecretManagerClientWithCache struct {
+	client gcpSecretManagerClient
+	cache  cache.Cache
+}
+
+func (c gcpSecretManagerClientWithCache) AccessSecretVersion(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest, opts ...gax.CallOption) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	if c.cache == nil {
+		return c.client.AccessSecretVersion(ctx, req, opts...)
+	}
+
+	value, err := client.GetWithCache(ctx, c.cache, req.GetName(), true, nil, func(ctx context.Context) (*string, error) {
+		output, err := c.client.AccessSecretVersion(ctx, req, opts...)
+		if err != nil {
+			return nil, err
+		}
+		value := string(output.GetPayload().GetData())
+		return &value, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &secretmanagerpb.AccessSecretVersionResponse{
+		Name:    req.GetName(),
+		Payload: &secretmanagerpb.SecretPayload{Data: []byte(*value)},
+	}, nil
+}
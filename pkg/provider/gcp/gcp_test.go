@@ -0,0 +1,318 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gcp
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/dwango/yashiro/internal/client/cache"
+	"github.com/dwango/yashiro/internal/values"
+	"github.com/dwango/yashiro/pkg/config"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeSecretManagerServer struct {
+	secretmanagerpb.UnimplementedSecretManagerServiceServer
+	secrets map[string]string
+}
+
+func (s fakeSecretManagerServer) AccessSecretVersion(_ context.Context, req *secretmanagerpb.AccessSecretVersionRequest) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	data, ok := s.secrets[req.GetName()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "secret not found: %s", req.GetName())
+	}
+
+	return &secretmanagerpb.AccessSecretVersionResponse{
+		Name:    req.GetName(),
+		Payload: &secretmanagerpb.SecretPayload{Data: []byte(data)},
+	}, nil
+}
+
+func Test_newGcpClient(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	srv := grpc.NewServer()
+	secretmanagerpb.RegisterSecretManagerServiceServer(srv, fakeSecretManagerServer{
+		secrets: map[string]string{
+			"projects/test/secrets/plain/versions/latest": "test",
+			"projects/test/secrets/json/versions/1":       `{"key":"value"}`,
+		},
+	})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	tests := []struct {
+		name           string
+		cfg            *config.Config
+		ignoreNotFound bool
+		want           values.Values
+		wantErr        bool
+	}{
+		{
+			name: "ok",
+			cfg: &config.Config{Gcp: &config.GcpConfig{
+				Project:  "test",
+				Endpoint: lis.Addr().String(),
+				Insecure: true,
+				SecretManagerValues: []config.GcpSecretManagerValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "plain"}},
+					{ValueConfig: config.ValueConfig{Name: "projects/test/secrets/json", Ref: stringPtr("json"), IsJSON: true}, Version: "1"},
+				},
+			}},
+			want: values.Values{"plain": "test", "json": map[string]any{"key": "value"}},
+		},
+		{
+			name: "ok: ignore not found error",
+			cfg: &config.Config{Gcp: &config.GcpConfig{
+				Project:  "test",
+				Endpoint: lis.Addr().String(),
+				Insecure: true,
+				SecretManagerValues: []config.GcpSecretManagerValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "notfound"}},
+				},
+			}},
+			ignoreNotFound: true,
+			want:           values.Values{},
+		},
+		{
+			name: "error: return not found",
+			cfg: &config.Config{Gcp: &config.GcpConfig{
+				Project:  "test",
+				Endpoint: lis.Addr().String(),
+				Insecure: true,
+				SecretManagerValues: []config.GcpSecretManagerValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "notfound"}},
+				},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := newGcpClient(tt.cfg)
+			if err != nil {
+				t.Fatalf("newGcpClient() error = %v", err)
+			}
+			got, err := c.GetValues(context.Background(), tt.ignoreNotFound)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("gcpClient.GetValues() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("gcpClient.GetValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_gcpSecretVersionName(t *testing.T) {
+	type args struct {
+		project string
+		v       config.GcpSecretManagerValueConfig
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "ok: secret id",
+			args: args{
+				project: "project",
+				v:       config.GcpSecretManagerValueConfig{ValueConfig: config.ValueConfig{Name: "secret"}},
+			},
+			want: "projects/project/secrets/secret/versions/latest",
+		},
+		{
+			name: "ok: secret id with version",
+			args: args{
+				project: "project",
+				v:       config.GcpSecretManagerValueConfig{ValueConfig: config.ValueConfig{Name: "secret"}, Version: "3"},
+			},
+			want: "projects/project/secrets/secret/versions/3",
+		},
+		{
+			name: "ok: secret resource name",
+			args: args{
+				v: config.GcpSecretManagerValueConfig{ValueConfig: config.ValueConfig{Name: "projects/other/secrets/secret"}},
+			},
+			want: "projects/other/secrets/secret/versions/latest",
+		},
+		{
+			name: "ok: version resource name",
+			args: args{
+				v: config.GcpSecretManagerValueConfig{ValueConfig: config.ValueConfig{Name: "projects/other/secrets/secret/versions/2"}},
+			},
+			want: "projects/other/secrets/secret/versions/2",
+		},
+		{
+			name: "error: project is empty",
+			args: args{
+				v: config.GcpSecretManagerValueConfig{ValueConfig: config.ValueConfig{Name: "secret"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: version conflicts",
+			args: args{
+				v: config.GcpSecretManagerValueConfig{ValueConfig: config.ValueConfig{Name: "projects/other/secrets/secret/versions/2"}, Version: "3"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: invalid name",
+			args: args{
+				v: config.GcpSecretManagerValueConfig{ValueConfig: config.ValueConfig{Name: "projects/other"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gcpSecretVersionName(tt.args.project, tt.args.v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("gcpSecretVersionName() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("gcpSecretVersionName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type mockGcpSecretManagerClient func(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest, opts ...gax.CallOption) (*secretmanagerpb.AccessSecretVersionResponse, error)
+
+func (m mockGcpSecretManagerClient) AccessSecretVersion(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest, opts ...gax.CallOption) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	return m(ctx, req, opts...)
+}
+
+func Test_gcpSecretManagerClientWithCache_AccessSecretVersion(t *testing.T) {
+	var req = &secretmanagerpb.AccessSecretVersionRequest{Name: "any"}
+	textStrGcpClient := mockGcpSecretManagerClient(func(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest, opts ...gax.CallOption) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+		return &secretmanagerpb.AccessSecretVersionResponse{
+			Name:    req.GetName(),
+			Payload: &secretmanagerpb.SecretPayload{Data: []byte("test")},
+		}, nil
+	})
+
+	type fields struct {
+		client gcpSecretManagerClient
+		cache  cache.Cache
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "ok: get from cache",
+			fields: fields{
+				client: nil,
+				cache:  mockCache{load: mockLoadFunc, save: mockSaveFunc},
+			},
+			want: "value",
+		},
+		{
+			name: "ok: get from client(cache disabled)",
+			fields: fields{
+				client: textStrGcpClient,
+				cache:  nil,
+			},
+			want: "test",
+		},
+		{
+			name: "ok: get from client(no cache)",
+			fields: fields{
+				client: textStrGcpClient,
+				cache:  mockCache{load: mockLoadFuncNotFound, save: mockSaveFunc},
+			},
+			want: "test",
+		},
+		{
+			name: "ok: get from client(cache expired)",
+			fields: fields{
+				client: textStrGcpClient,
+				cache:  mockCache{load: mockLoadFuncExpired, save: mockSaveFunc},
+			},
+			want: "test",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gcpSecretManagerClientWithCache{
+				client: tt.fields.client,
+				cache:  tt.fields.cache,
+			}
+			got, err := c.AccessSecretVersion(context.Background(), req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("gcpSecretManagerClientWithCache.AccessSecretVersion() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if string(got.GetPayload().GetData()) != tt.want {
+				t.Errorf("gcpSecretManagerClientWithCache.AccessSecretVersion() = %v, want %v", string(got.GetPayload().GetData()), tt.want)
+			}
+		})
+	}
+}
+
+var (
+	mockLoadFunc = func(_ context.Context, key string, decrypt bool) (*string, bool, error) {
+		return stringPtr("value"), false, nil
+	}
+	mockLoadFuncNotFound = func(_ context.Context, key string, decrypt bool) (*string, bool, error) {
+		return nil, true, nil
+	}
+	mockLoadFuncExpired = func(_ context.Context, key string, decrypt bool) (*string, bool, error) {
+		return stringPtr("value"), true, nil
+	}
+	mockSaveFunc = func(_ context.Context, key string, value *string, encrypt bool) error {
+		return nil
+	}
+)
+
+type mockCache struct {
+	load func(ctx context.Context, key string, decrypt bool) (*string, bool, error)
+	save func(ctx context.Context, key string, value *string, encrypt bool) error
+}
+
+func (m mockCache) Load(ctx context.Context, key string, decrypt bool) (*string, bool, error) {
+	return m.load(ctx, key, decrypt)
+}
+
+func (m mockCache) LoadStale(_ context.Context, _ string, _ bool) (*string, error) {
+	return nil, nil
+}
+
+func (m mockCache) Save(ctx context.Context, key string, value *string, encrypt bool) error {
+	return m.save(ctx, key, value, encrypt)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
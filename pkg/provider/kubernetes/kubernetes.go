@@ -0,0 +1,278 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package kubernetes provides values from Kubernetes Secrets and ConfigMaps. It registers the
+// "kubernetes" provider when it is imported:
+//
+//	import _ "github.com/dwango/yashiro/pkg/provider/kubernetes"
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dwango/yashiro/internal/client"
+	"github.com/dwango/yashiro/internal/client/cache"
+	"github.com/dwango/yashiro/internal/localfile"
+	"github.com/dwango/yashiro/internal/values"
+	"github.com/dwango/yashiro/pkg/config"
+	"github.com/dwango/yashiro/pkg/provider"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func init() {
+	provider.Register("kubernetes", newKubernetesClient)
+}
+
+var errKeyNotFound = errors.New("key not found")
+
+type kubernetesKind string
+
+const (
+	kubernetesKindSecret    kubernetesKind = "secrets"
+	kubernetesKindConfigMap kubernetesKind = "configmaps"
+)
+
+type kubernetesClient struct {
+	objectClient   kubernetesObjectClient
+	namespace      string
+	secretValue    []config.KubernetesValueConfig
+	configMapValue []config.KubernetesValueConfig
+}
+
+func newKubernetesClient(cfg *config.Config) (provider.Provider, error) {
+	if cfg.Kubernetes == nil {
+		return nil, nil
+	}
+
+	kubeconfig, err := kubeconfigPath(cfg.Kubernetes.BaseDir, cfg.Kubernetes.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules,
+		&clientcmd.ConfigOverrides{CurrentContext: cfg.Kubernetes.Context},
+	)
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := cfg.Kubernetes.Namespace
+	if len(namespace) == 0 {
+		namespace, _, err = clientConfig.Namespace()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var cc cache.Cache
+	if cfg.Global.EnableCache {
+		cc, err = cache.New(cfg.Global.Cache, cache.WithCacheKeys("kubernetes", restConfig.Host))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &kubernetesClient{
+		objectClient: &kubernetesObjectClientWithCache{
+			client: &kubernetesObjectReader{clientset: clientset},
+			cache:  cc,
+		},
+		namespace:      namespace,
+		secretValue:    cfg.Kubernetes.SecretValues,
+		configMapValue: cfg.Kubernetes.ConfigMapValues,
+	}, nil
+}
+
+// kubeconfigPath expands leading "~" of path to the home directory, and resolves a relative path
+// from baseDir. If path is empty, it returns empty to use the default loading rules.
+func kubeconfigPath(baseDir, path string) (string, error) {
+	if len(path) == 0 {
+		return "", nil
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to expand kubeconfig path '%s': %w", path, err)
+		}
+		return filepath.Join(home, path[1:]), nil
+	}
+
+	return localfile.ResolvePath(baseDir, path), nil
+}
+
+func (c kubernetesClient) GetValues(ctx context.Context, ignoreNotFound bool) (values.Values, error) {
+	values := make(values.Values, len(c.secretValue)+len(c.configMapValue))
+
+	for _, objects := range []struct {
+		kind   kubernetesKind
+		values []config.KubernetesValueConfig
+	}{
+		{kind: kubernetesKindSecret, values: c.secretValue},
+		{kind: kubernetesKindConfigMap, values: c.configMapValue},
+	} {
+		for _, v := range objects.values {
+			namespace := v.Namespace
+			if len(namespace) == 0 {
+				namespace = c.namespace
+			}
+
+			var value *string
+			data, err := c.objectClient.GetData(ctx, objects.kind, namespace, v.Name)
+			if err == nil {
+				value, err = kubernetesDataValue(&v, data)
+			}
+
+			if err != nil {
+				if ignoreNotFound && (apierrors.IsNotFound(err) || errors.Is(err, errKeyNotFound)) {
+					continue
+				}
+				return nil, client.GettingValueError(namespace+"/"+v.Name, err)
+			}
+
+			if err := values.SetValue(v, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// GetValuesByKeys gets only Secrets and ConfigMaps referenced by keys.
+func (c kubernetesClient) GetValuesByKeys(ctx context.Context, keys []string, ignoreNotFound bool) (values.Values, error) {
+	c.secretValue = client.SelectValues(c.secretValue, keys, client.ReferenceName)
+	c.configMapValue = client.SelectValues(c.configMapValue, keys, client.ReferenceName)
+
+	return c.GetValues(ctx, ignoreNotFound)
+}
+
+// kubernetesDataValue returns the value of Key in the data. If Key is empty, returns all data as
+// JSON string and v is set to be decoded as JSON.
+func kubernetesDataValue(v *config.KubernetesValueConfig, data map[string]string) (*string, error) {
+	if len(v.Key) == 0 {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		value := string(b)
+		v.IsJSON = true
+		return &value, nil
+	}
+
+	value, ok := data[v.Key]
+	if !ok {
+		return nil, fmt.Errorf("%w: key='%s'", errKeyNotFound, v.Key)
+	}
+
+	return &value, nil
+}
+
+type kubernetesObjectClient interface {
+	GetData(ctx context.Context, kind kubernetesKind, namespace, name string) (map[string]string, error)
+}
+
+type kubernetesObjectClientWithCache struct {
+	client kubernetesObjectClient
+	cache  cache.Cache
+}
+
+func (c kubernetesObjectClientWithCache) GetData(ctx context.Context, kind kubernetesKind, namespace, name string) (map[string]string, error) {
+	if c.cache == nil {
+		return c.client.GetData(ctx, kind, namespace, name)
+	}
+
+	key := fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+	isSensitive := kind == kubernetesKindSecret
+
+	value, err := client.GetWithCache(ctx, c.cache, key, isSensitive, nil, func(ctx context.Context) (*string, error) {
+		data, err := c.client.GetData(ctx, kind, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		value := string(b)
+
+		return &value, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]string)
+	if err := json.Unmarshal([]byte(*value), &data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// kubernetesObjectReader reads data of Secrets and ConfigMaps from the Kubernetes API.
+type kubernetesObjectReader struct {
+	clientset kubernetes.Interface
+}
+
+func (r kubernetesObjectReader) GetData(ctx context.Context, kind kubernetesKind, namespace, name string) (map[string]string, error) {
+	data := make(map[string]string)
+
+	switch kind {
+	case kubernetesKindSecret:
+		secret, err := r.clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range secret.Data {
+			data[k] = string(v)
+		}
+	case kubernetesKindConfigMap:
+		configMap, err := r.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range configMap.BinaryData {
+			data[k] = string(v)
+		}
+		for k, v := range configMap.Data {
+			data[k] = v
+		}
+	default:
+		return nil, fmt.Errorf("invalid kind: %s", kind)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,393 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kubernetes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dwango/yashiro/internal/client/cache"
+	"github.com/dwango/yashiro/internal/values"
+	"github.com/dwango/yashiro/pkg/config"
+	"github.com/dwango/yashiro/pkg/provider"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_newKubernetesClient(t *testing.T) {
+	type args struct {
+		cfg *config.Config
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    provider.Provider
+		wantErr bool
+	}{
+		{
+			name: "ok: kubernetes is not configured",
+			args: args{
+				cfg: &config.Config{},
+			},
+			want: nil,
+		},
+		{
+			name: "error: kubeconfig is not found",
+			args: args{
+				cfg: &config.Config{Kubernetes: &config.KubernetesConfig{
+					Kubeconfig: "testdata/notfound",
+				}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newKubernetesClient(tt.args.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newKubernetesClient() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newKubernetesClient() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_newKubernetesClient_kubeconfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	baseDir := t.TempDir()
+
+	kubeconfig := []byte(`apiVersion: v1
+kind: Config
+clusters:
+  - name: cluster
+    cluster:
+      server: https://k8s.example.com
+contexts:
+  - name: production
+    context:
+      cluster: cluster
+      namespace: shared
+current-context: production
+`)
+	for _, path := range []string{filepath.Join(home, ".kube", "other-cluster"), filepath.Join(baseDir, "kubeconfig")} {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, kubeconfig, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		kubeconfig string
+	}{
+		{
+			name:       "ok: home directory",
+			kubeconfig: "~/.kube/other-cluster",
+		},
+		{
+			name:       "ok: relative path from the configuration file",
+			kubeconfig: "./kubeconfig",
+		},
+		{
+			name:       "ok: absolute path",
+			kubeconfig: filepath.Join(baseDir, "kubeconfig"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newKubernetesClient(&config.Config{Kubernetes: &config.KubernetesConfig{
+				Kubeconfig: tt.kubeconfig,
+				BaseDir:    baseDir,
+			}})
+			if err != nil {
+				t.Errorf("newKubernetesClient() error = %v", err)
+				return
+			}
+			if namespace := got.(*kubernetesClient).namespace; namespace != "shared" {
+				t.Errorf("newKubernetesClient() namespace = %v, want %v", namespace, "shared")
+			}
+		})
+	}
+}
+
+func Test_kubeconfigPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		name    string
+		baseDir string
+		path    string
+		want    string
+	}{
+		{
+			name:    "ok: empty",
+			baseDir: "/etc/yashiro",
+			path:    "",
+			want:    "",
+		},
+		{
+			name:    "ok: home directory",
+			baseDir: "/etc/yashiro",
+			path:    "~/.kube/config",
+			want:    filepath.Join(home, ".kube", "config"),
+		},
+		{
+			name:    "ok: relative path",
+			baseDir: "/etc/yashiro",
+			path:    "kube/config",
+			want:    filepath.Join("/etc/yashiro", "kube", "config"),
+		},
+		{
+			name:    "ok: absolute path",
+			baseDir: "/etc/yashiro",
+			path:    "/root/.kube/config",
+			want:    "/root/.kube/config",
+		},
+		{
+			name:    "ok: ~user is not expanded",
+			baseDir: "/etc/yashiro",
+			path:    "~kube",
+			want:    filepath.Join("/etc/yashiro", "~kube"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := kubeconfigPath(tt.baseDir, tt.path)
+			if err != nil {
+				t.Errorf("kubeconfigPath() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("kubeconfigPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_kubernetesClient_GetValues(t *testing.T) {
+	reader := &kubernetesObjectReader{
+		clientset: fake.NewClientset(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"},
+				Data:       map[string][]byte{"username": []byte("user"), "password": []byte("pass")},
+			},
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "app"},
+				Data:       map[string]string{"config.json": `{"key":"value"}`},
+			},
+		),
+	}
+
+	type fields struct {
+		secretValue    []config.KubernetesValueConfig
+		configMapValue []config.KubernetesValueConfig
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		ignoreNotFound bool
+		want           values.Values
+		wantErr        bool
+	}{
+		{
+			name: "ok: key",
+			fields: fields{
+				secretValue: []config.KubernetesValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "db", Ref: stringPtr("password")}, Key: "password"},
+				},
+				configMapValue: []config.KubernetesValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "app", Ref: stringPtr("config"), IsJSON: true}, Namespace: "other", Key: "config.json"},
+				},
+			},
+			want: values.Values{"password": "pass", "config": map[string]any{"key": "value"}},
+		},
+		{
+			name: "ok: whole object",
+			fields: fields{
+				secretValue: []config.KubernetesValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "db"}},
+				},
+			},
+			want: values.Values{"db": map[string]any{"username": "user", "password": "pass"}},
+		},
+		{
+			name: "ok: ignore not found error",
+			fields: fields{
+				secretValue: []config.KubernetesValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "notfound"}},
+					{ValueConfig: config.ValueConfig{Name: "db"}, Key: "notfound"},
+				},
+			},
+			ignoreNotFound: true,
+			want:           values.Values{},
+		},
+		{
+			name: "error: return not found",
+			fields: fields{
+				configMapValue: []config.KubernetesValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "app"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: not found key",
+			fields: fields{
+				secretValue: []config.KubernetesValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "db"}, Key: "notfound"},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := kubernetesClient{
+				objectClient:   reader,
+				namespace:      "default",
+				secretValue:    tt.fields.secretValue,
+				configMapValue: tt.fields.configMapValue,
+			}
+			got, err := c.GetValues(context.Background(), tt.ignoreNotFound)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("kubernetesClient.GetValues() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kubernetesClient.GetValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type mockKubernetesObjectClient func(ctx context.Context, kind kubernetesKind, namespace, name string) (map[string]string, error)
+
+func (m mockKubernetesObjectClient) GetData(ctx context.Context, kind kubernetesKind, namespace, name string) (map[string]string, error) {
+	return m(ctx, kind, namespace, name)
+}
+
+func Test_kubernetesObjectClientWithCache_GetData(t *testing.T) {
+	dataClient := mockKubernetesObjectClient(func(ctx context.Context, kind kubernetesKind, namespace, name string) (map[string]string, error) {
+		return map[string]string{"key": "test"}, nil
+	})
+
+	type fields struct {
+		client kubernetesObjectClient
+		cache  cache.Cache
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		kind          kubernetesKind
+		wantSensitive bool
+		want          map[string]string
+	}{
+		{
+			name: "ok: get secret from cache",
+			fields: fields{
+				client: nil,
+			},
+			kind:          kubernetesKindSecret,
+			wantSensitive: true,
+			want:          map[string]string{"key": "value"},
+		},
+		{
+			name: "ok: get configmap from cache",
+			fields: fields{
+				client: nil,
+			},
+			kind:          kubernetesKindConfigMap,
+			wantSensitive: false,
+			want:          map[string]string{"key": "value"},
+		},
+		{
+			name: "ok: get from client(cache disabled)",
+			fields: fields{
+				client: dataClient,
+				cache:  nil,
+			},
+			kind: kubernetesKindSecret,
+			want: map[string]string{"key": "test"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := tt.fields.cache
+			if tt.fields.client == nil {
+				cc = mockCache{
+					load: func(_ context.Context, key string, decrypt bool) (*string, bool, error) {
+						if decrypt != tt.wantSensitive {
+							t.Errorf("cache.Load() decrypt = %v, want %v", decrypt, tt.wantSensitive)
+						}
+						return stringPtr(`{"key":"value"}`), false, nil
+					},
+					save: mockSaveFunc,
+				}
+			}
+			c := kubernetesObjectClientWithCache{
+				client: tt.fields.client,
+				cache:  cc,
+			}
+			got, err := c.GetData(context.Background(), tt.kind, "default", "any")
+			if err != nil {
+				t.Errorf("kubernetesObjectClientWithCache.GetData() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kubernetesObjectClientWithCache.GetData() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+var (
+	mockSaveFunc = func(_ context.Context, key string, value *string, encrypt bool) error {
+		return nil
+	}
+)
+
+type mockCache struct {
+	load func(ctx context.Context, key string, decrypt bool) (*string, bool, error)
+	save func(ctx context.Context, key string, value *string, encrypt bool) error
+}
+
+func (m mockCache) Load(ctx context.Context, key string, decrypt bool) (*string, bool, error) {
+	return m.load(ctx, key, decrypt)
+}
+
+func (m mockCache) LoadStale(_ context.Context, _ string, _ bool) (*string, error) {
+	return nil, nil
+}
+
+func (m mockCache) Save(ctx context.Context, key string, value *string, encrypt bool) error {
+	return m.save(ctx, key, value, encrypt)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,122 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provider defines the interface of external value stores and the registry of them.
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/dwango/yashiro/internal/values"
+	"github.com/dwango/yashiro/pkg/config"
+)
+
+// Values are stored values from external stores.
+type Values = values.Values
+
+// Provider is an external store which provides values referenced from templates.
+type Provider interface {
+	// GetValues returns values from the external store. If ignoreNotFound is true, values
+	// which are not found in the external store are skipped.
+	GetValues(ctx context.Context, ignoreNotFound bool) (Values, error)
+}
+
+// SelectiveProvider is a Provider which can get only values referenced by specific top-level
+// keys, so that values which are not used are not fetched from the external store.
+type SelectiveProvider interface {
+	Provider
+	// GetValuesByKeys returns values like GetValues, but values whose top-level key is not in
+	// keys may be skipped.
+	GetValuesByKeys(ctx context.Context, keys []string, ignoreNotFound bool) (Values, error)
+}
+
+// GetValuesByKeys returns values of the top-level keys from p. If p is a SelectiveProvider,
+// GetValuesByKeys uses p.GetValuesByKeys. Otherwise, it gets all values by p.GetValues.
+func GetValuesByKeys(ctx context.Context, p Provider, keys []string, ignoreNotFound bool) (Values, error) {
+	if sp, ok := p.(SelectiveProvider); ok {
+		return sp.GetValuesByKeys(ctx, keys, ignoreNotFound)
+	}
+
+	return p.GetValues(ctx, ignoreNotFound)
+}
+
+// Factory returns a new Provider according to the configuration. If the provider is not
+// configured, Factory returns nil Provider and nil error.
+type Factory func(cfg *config.Config) (Provider, error)
+
+type registration struct {
+	name    string
+	factory Factory
+}
+
+var (
+	mu            sync.RWMutex
+	registrations []registration
+)
+
+// Register makes a provider available by the name. Providers are built in registration order,
+// and values of a later provider overwrite values of an earlier one which have the same reference
+// name. If Register is called twice with the same name or if factory is nil, it panics.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if factory == nil {
+		panic("provider: Register factory is nil")
+	}
+	for _, r := range registrations {
+		if r.name == name {
+			panic(fmt.Sprintf("provider: Register called twice for provider '%s'", name))
+		}
+	}
+
+	registrations = append(registrations, registration{name: name, factory: factory})
+}
+
+// Providers returns the names of registered providers in registration order.
+func Providers() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(registrations))
+	for _, r := range registrations {
+		names = append(names, r.name)
+	}
+
+	return names
+}
+
+// New returns configured providers built by all registered factories.
+func New(cfg *config.Config) ([]Provider, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	providers := make([]Provider, 0, len(registrations))
+	for _, r := range registrations {
+		p, err := r.factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create provider '%s': %w", r.name, err)
+		}
+		if p == nil {
+			continue
+		}
+		providers = append(providers, p)
+	}
+
+	return providers, nil
+}
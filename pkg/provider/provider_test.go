@@ -0,0 +1,192 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dwango/yashiro/pkg/config"
+)
+
+type mockProvider func(ctx context.Context, ignoreNotFound bool) (Values, error)
+
+func (m mockProvider) GetValues(ctx context.Context, ignoreNotFound bool) (Values, error) {
+	return m(ctx, ignoreNotFound)
+}
+
+type mockSelectiveProvider struct {
+	mockProvider
+	getValuesByKeys func(ctx context.Context, keys []string, ignoreNotFound bool) (Values, error)
+}
+
+func (m mockSelectiveProvider) GetValuesByKeys(ctx context.Context, keys []string, ignoreNotFound bool) (Values, error) {
+	return m.getValuesByKeys(ctx, keys, ignoreNotFound)
+}
+
+// resetRegistrations replaces registrations for a test and restores them after the test.
+func resetRegistrations(t *testing.T) {
+	t.Helper()
+
+	saved := registrations
+	registrations = nil
+	t.Cleanup(func() { registrations = saved })
+}
+
+func TestRegister(t *testing.T) {
+	resetRegistrations(t)
+
+	factory := func(*config.Config) (Provider, error) { return nil, nil }
+	Register("first", factory)
+	Register("second", factory)
+
+	if got, want := Providers(), []string{"first", "second"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Providers() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name     string
+		provider string
+		factory  Factory
+	}{
+		{
+			name:     "panic: registered twice",
+			provider: "first",
+			factory:  factory,
+		},
+		{
+			name:     "panic: factory is nil",
+			provider: "third",
+			factory:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Register() does not panic")
+				}
+			}()
+			Register(tt.provider, tt.factory)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	configured := mockProvider(func(context.Context, bool) (Values, error) {
+		return Values{"key": "value"}, nil
+	})
+
+	tests := []struct {
+		name          string
+		registrations []registration
+		wantLen       int
+		wantErr       bool
+	}{
+		{
+			name: "ok: skip not configured provider",
+			registrations: []registration{
+				{name: "configured", factory: func(*config.Config) (Provider, error) { return configured, nil }},
+				{name: "not-configured", factory: func(*config.Config) (Provider, error) { return nil, nil }},
+			},
+			wantLen: 1,
+		},
+		{
+			name:          "ok: no providers",
+			registrations: nil,
+			wantLen:       0,
+		},
+		{
+			name: "error: factory returns error",
+			registrations: []registration{
+				{name: "error", factory: func(*config.Config) (Provider, error) { return nil, errors.New("error") }},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistrations(t)
+			registrations = tt.registrations
+
+			got, err := New(&config.Config{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(got) != tt.wantLen {
+				t.Errorf("New() = %v, want length %v", got, tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestGetValuesByKeys(t *testing.T) {
+	all := mockProvider(func(context.Context, bool) (Values, error) {
+		return Values{"key1": "value1", "key2": "value2"}, nil
+	})
+
+	type args struct {
+		p    Provider
+		keys []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want Values
+	}{
+		{
+			name: "ok: selective provider",
+			args: args{
+				p: mockSelectiveProvider{
+					mockProvider: all,
+					getValuesByKeys: func(_ context.Context, keys []string, _ bool) (Values, error) {
+						v := Values{}
+						for _, k := range keys {
+							v[k] = "selected"
+						}
+						return v, nil
+					},
+				},
+				keys: []string{"key1"},
+			},
+			want: Values{"key1": "selected"},
+		},
+		{
+			name: "ok: fall back to all values",
+			args: args{
+				p:    all,
+				keys: []string{"key1"},
+			},
+			want: Values{"key1": "value1", "key2": "value2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetValuesByKeys(context.Background(), tt.args.p, tt.args.keys, false)
+			if err != nil {
+				t.Errorf("GetValuesByKeys() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetValuesByKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sops provides values from SOPS encrypted files. It registers the "sops" provider when
+// it is imported:
+//
+//	import _ "github.com/dwango/yashiro/pkg/provider/sops"
+package sops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/dwango/yashiro/internal/client"
+	"github.com/dwango/yashiro/internal/localfile"
+	"github.com/dwango/yashiro/internal/values"
+	"github.com/dwango/yashiro/pkg/config"
+	"github.com/dwango/yashiro/pkg/provider"
+	"github.com/getsops/sops/v3/decrypt"
+)
+
+func init() {
+	provider.Register("sops", newSopsClient)
+}
+
+type sopsClient struct {
+	baseDir   string
+	fileValue []config.FileValueConfig
+}
+
+func newSopsClient(cfg *config.Config) (provider.Provider, error) {
+	if cfg.Sops == nil {
+		return nil, nil
+	}
+
+	return &sopsClient{
+		baseDir:   cfg.Sops.BaseDir,
+		fileValue: cfg.Sops.Values,
+	}, nil
+}
+
+func (c sopsClient) GetValues(_ context.Context, ignoreNotFound bool) (values.Values, error) {
+	values := make(values.Values)
+
+	for _, v := range c.fileValue {
+		path := localfile.ResolvePath(c.baseDir, v.Path)
+		format := v.Format
+		if format == config.FileFormatUnspecified {
+			format = localfile.FormatFromPath(path)
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			if ignoreNotFound && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, client.GettingValueError(v.Path, err)
+		}
+
+		b, err = decryptSops(b, format)
+		if err != nil {
+			return nil, client.GettingValueError(v.Path, err)
+		}
+
+		content, err := localfile.Parse(b, format)
+		if err != nil {
+			return nil, client.GettingValueError(v.Path, err)
+		}
+
+		localfile.SetValues(values, v, content)
+	}
+
+	return values, nil
+}
+
+// GetValuesByKeys decrypts only files whose Ref is in keys, and files without Ref.
+func (c sopsClient) GetValuesByKeys(ctx context.Context, keys []string, ignoreNotFound bool) (values.Values, error) {
+	c.fileValue = client.SelectValues(c.fileValue, keys, localfile.ReferenceName)
+
+	return c.GetValues(ctx, ignoreNotFound)
+}
+
+// decryptSops decrypts a SOPS encrypted file. The MAC of the file is verified.
+func decryptSops(b []byte, format config.FileFormat) ([]byte, error) {
+	switch format {
+	case config.FileFormatJSON, config.FileFormatYAML, config.FileFormatDotenv:
+		return decrypt.Data(b, string(format))
+	default:
+		return nil, fmt.Errorf("invalid file format: %s", format)
+	}
+}
@@ -0,0 +1,148 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sops
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/dwango/yashiro/internal/values"
+	"github.com/dwango/yashiro/pkg/config"
+)
+
+// Age keys only for tests. Files in testdata are encrypted with testAgeKey.
+const (
+	testAgeKey  = "AGE-SECRET-KEY-1FHHYHJY2CMDUYS5PWRDLHXPENCYQTCESFKR36MV8YS3DK409MCDQQQ2H5T"
+	otherAgeKey = "AGE-SECRET-KEY-1XX775FUV4HRFQU3GMK3TX43SFAAR7HN09NACFTKRVJUETAC5DMNQYUH9S9"
+)
+
+func Test_sopsClient_GetValues(t *testing.T) {
+	type fields struct {
+		fileValue []config.FileValueConfig
+	}
+	type args struct {
+		ctx            context.Context
+		ignoreNotFound bool
+	}
+	tests := []struct {
+		name    string
+		ageKey  string
+		fields  fields
+		args    args
+		want    values.Values
+		wantErr bool
+	}{
+		{
+			name:   "ok: yaml without ref",
+			ageKey: testAgeKey,
+			fields: fields{
+				fileValue: []config.FileValueConfig{
+					{Path: "secrets.yaml"},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			want: values.Values{
+				"db":     map[string]any{"username": "user", "password": "pass"},
+				"apiKey": "secret-api-key",
+			},
+		},
+		{
+			name:   "ok: json and dotenv with ref",
+			ageKey: testAgeKey,
+			fields: fields{
+				fileValue: []config.FileValueConfig{
+					{Path: "secrets.json", Ref: stringPtr("json")},
+					{Path: "secrets.env", Ref: stringPtr("env")},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			want: values.Values{
+				"json": map[string]any{"token": "secret-token"},
+				"env":  map[string]any{"DB_PASSWORD": "pass"},
+			},
+		},
+		{
+			name:   "ok: ignore not found error",
+			ageKey: testAgeKey,
+			fields: fields{
+				fileValue: []config.FileValueConfig{
+					{Path: "notfound.yaml"},
+				},
+			},
+			args: args{
+				ctx:            context.Background(),
+				ignoreNotFound: true,
+			},
+			want: values.Values{},
+		},
+		{
+			name:   "error: wrong key",
+			ageKey: otherAgeKey,
+			fields: fields{
+				fileValue: []config.FileValueConfig{
+					{Path: "secrets.yaml"},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			wantErr: true,
+		},
+		{
+			name:   "error: tampered file",
+			ageKey: testAgeKey,
+			fields: fields{
+				fileValue: []config.FileValueConfig{
+					{Path: "tampered.yaml"},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SOPS_AGE_KEY", tt.ageKey)
+			t.Setenv("SOPS_AGE_KEY_FILE", "")
+			t.Setenv("SOPS_AGE_SSH_PRIVATE_KEY_FILE", "")
+
+			c := sopsClient{
+				baseDir:   "testdata",
+				fileValue: tt.fields.fileValue,
+			}
+			got, err := c.GetValues(tt.args.ctx, tt.args.ignoreNotFound)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("sopsClient.GetValues() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sopsClient.GetValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
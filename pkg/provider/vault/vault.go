@@ -0,0 +1,409 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package vault provides values from HashiCorp Vault KV secrets engines. It registers the "vault"
+// provider when it is imported:
+//
+//	import _ "github.com/dwango/yashiro/pkg/provider/vault"
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dwango/yashiro/internal/client"
+	"github.com/dwango/yashiro/internal/client/cache"
+	"github.com/dwango/yashiro/internal/localfile"
+	"github.com/dwango/yashiro/internal/values"
+	"github.com/dwango/yashiro/pkg/config"
+	"github.com/dwango/yashiro/pkg/provider"
+	vault "github.com/hashicorp/vault/api"
+)
+
+func init() {
+	provider.Register("vault", newVaultClient)
+}
+
+type vaultClient struct {
+	kvClient kvClient
+	kvValue  []config.VaultKvValueConfig
+}
+
+func newVaultClient(cfg *config.Config) (provider.Provider, error) {
+	if cfg.Vault == nil {
+		return nil, nil
+	}
+
+	vaultCfg := vault.DefaultConfig()
+	if vaultCfg.Error != nil {
+		return nil, vaultCfg.Error
+	}
+	if len(cfg.Vault.Address) != 0 {
+		vaultCfg.Address = cfg.Vault.Address
+	}
+
+	cli, err := vault.NewClient(vaultCfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Vault.Namespace) != 0 {
+		cli.SetNamespace(cfg.Vault.Namespace)
+	}
+
+	login, err := newVaultLogin(cfg.Vault.Auth, cfg.Vault.BaseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var cc cache.Cache
+	if cfg.Global.EnableCache {
+		cc, err = cache.New(cfg.Global.Cache, cache.WithCacheKeys("vault", vaultCfg.Address, cfg.Vault.Namespace))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &vaultClient{
+		kvClient: &kvClientWithCache{
+			client: &vaultKvClient{client: cli, login: login},
+			cache:  cc,
+		},
+		kvValue: cfg.Vault.KvValues,
+	}, nil
+}
+
+func (c vaultClient) GetValues(ctx context.Context, ignoreNotFound bool) (values.Values, error) {
+	values := make(values.Values, len(c.kvValue))
+
+	for _, v := range c.kvValue {
+		var value *string
+		data, err := c.kvClient.ReadSecret(ctx, v)
+		if err == nil {
+			value, err = vaultSecretValue(v, data)
+		}
+
+		if err != nil {
+			if ignoreNotFound && errors.Is(err, vault.ErrSecretNotFound) {
+				continue
+			}
+			return nil, client.GettingValueError(v.Name, err)
+		}
+
+		if err := values.SetValue(v, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// GetValuesByKeys reads only secrets referenced by keys.
+func (c vaultClient) GetValuesByKeys(ctx context.Context, keys []string, ignoreNotFound bool) (values.Values, error) {
+	c.kvValue = client.SelectValues(c.kvValue, keys, client.ReferenceName)
+
+	return c.GetValues(ctx, ignoreNotFound)
+}
+
+// vaultSecretValue returns the value of Key in the secret data. If Key is empty, returns all
+// data as JSON string.
+func vaultSecretValue(v config.VaultKvValueConfig, data map[string]any) (*string, error) {
+	if len(v.Key) == 0 {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		value := string(b)
+		return &value, nil
+	}
+
+	raw, ok := data[v.Key]
+	if !ok {
+		return nil, fmt.Errorf("%w: key='%s'", vault.ErrSecretNotFound, v.Key)
+	}
+
+	if value, ok := raw.(string); ok {
+		return &value, nil
+	}
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	value := string(b)
+
+	return &value, nil
+}
+
+type kvClient interface {
+	ReadSecret(ctx context.Context, v config.VaultKvValueConfig) (map[string]any, error)
+}
+
+type kvClientWithCache struct {
+	client kvClient
+	cache  cache.Cache
+}
+
+func (c kvClientWithCache) ReadSecret(ctx context.Context, v config.VaultKvValueConfig) (map[string]any, error) {
+	if c.cache == nil {
+		return c.client.ReadSecret(ctx, v)
+	}
+
+	value, err := client.GetWithCache(ctx, c.cache, vaultKvCacheKey(v), true, nil, func(ctx context.Context) (*string, error) {
+		data, err := c.client.ReadSecret(ctx, v)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		value := string(b)
+
+		return &value, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Numbers are kept as json.Number, as the Vault client does, so that large integers are not
+	// rounded to float64.
+	data := make(map[string]any)
+	decoder := json.NewDecoder(strings.NewReader(*value))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func vaultKvCacheKey(v config.VaultKvValueConfig) string {
+	key := strings.Join([]string{vaultKvMount(v), strconv.Itoa(vaultKvEngineVersion(v)), v.Name}, "/")
+	if v.Version != 0 {
+		key += "@" + strconv.Itoa(v.Version)
+	}
+
+	return key
+}
+
+// vaultKvClient reads secrets from KV secrets engines. It logs in to Vault before the first
+// request, and logs in again before the token expires or when the token is rejected.
+type vaultKvClient struct {
+	client *vault.Client
+	login  vaultLogin
+
+	mu       sync.Mutex
+	loggedIn bool
+	// reacquirable is true if the token is got by logging in, not given directly.
+	reacquirable bool
+	// expiresAt is the time to log in again. It is zero if the token does not expire.
+	expiresAt time.Time
+}
+
+func (c *vaultKvClient) ReadSecret(ctx context.Context, v config.VaultKvValueConfig) (map[string]any, error) {
+	if err := c.ensureLogin(ctx, false); err != nil {
+		return nil, err
+	}
+
+	data, err := c.readSecret(ctx, v)
+	// The token can be revoked or expire before its TTL, e.g. by the max TTL of the role.
+	var respErr *vault.ResponseError
+	if errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden && c.isReacquirable() {
+		if err := c.ensureLogin(ctx, true); err != nil {
+			return nil, err
+		}
+		data, err = c.readSecret(ctx, v)
+	}
+
+	return data, err
+}
+
+func (c *vaultKvClient) readSecret(ctx context.Context, v config.VaultKvValueConfig) (map[string]any, error) {
+	var secret *vault.KVSecret
+	var err error
+	switch vaultKvEngineVersion(v) {
+	case 1:
+		if v.Version != 0 {
+			return nil, fmt.Errorf("version is not supported by kv version 1")
+		}
+		secret, err = c.client.KVv1(vaultKvMount(v)).Get(ctx, v.Name)
+	case 2:
+		if v.Version != 0 {
+			secret, err = c.client.KVv2(vaultKvMount(v)).GetVersion(ctx, v.Name, v.Version)
+		} else {
+			secret, err = c.client.KVv2(vaultKvMount(v)).Get(ctx, v.Name)
+		}
+	default:
+		return nil, fmt.Errorf("invalid kv engine version: %d", v.EngineVersion)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return secret.Data, nil
+}
+
+// ensureLogin logs in if the client has not logged in or the token is about to expire. If force
+// is true, it always logs in.
+func (c *vaultKvClient) ensureLogin(ctx context.Context, force bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.login == nil {
+		return nil
+	}
+	if !force && c.loggedIn && (c.expiresAt.IsZero() || time.Now().Before(c.expiresAt)) {
+		return nil
+	}
+
+	auth, err := c.login(ctx, c.client)
+	if err != nil {
+		return fmt.Errorf("failed to login to vault: %w", err)
+	}
+	c.loggedIn = true
+	c.reacquirable = auth != nil
+	c.expiresAt = time.Time{}
+	if auth != nil && auth.LeaseDuration > 0 {
+		ttl := time.Duration(auth.LeaseDuration) * time.Second
+		// Log in again a little before the token expires.
+		c.expiresAt = time.Now().Add(ttl - ttl/10)
+	}
+
+	return nil
+}
+
+func (c *vaultKvClient) isReacquirable() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.reacquirable
+}
+
+func vaultKvMount(v config.VaultKvValueConfig) string {
+	if len(v.Mount) != 0 {
+		return v.Mount
+	}
+	return config.DefaultVaultKvMount
+}
+
+func vaultKvEngineVersion(v config.VaultKvValueConfig) int {
+	if v.EngineVersion != 0 {
+		return v.EngineVersion
+	}
+	return config.DefaultVaultKvEngineVersion
+}
+
+// vaultLogin sets a token to the client. It returns the auth info of the token got by logging in,
+// or nil if the token is given directly.
+type vaultLogin func(ctx context.Context, client *vault.Client) (*vault.SecretAuth, error)
+
+func newVaultLogin(cfg config.VaultAuthConfig, baseDir string) (vaultLogin, error) {
+	method := cfg.Method
+	if len(method) == 0 {
+		method = config.VaultAuthMethodToken
+	}
+	mountPath := cfg.MountPath
+	if len(mountPath) == 0 {
+		mountPath = string(method)
+	}
+
+	switch method {
+	case config.VaultAuthMethodToken:
+		return func(_ context.Context, client *vault.Client) (*vault.SecretAuth, error) {
+			// If token is empty, the client uses VAULT_TOKEN environment variable.
+			if len(cfg.Token) != 0 {
+				client.SetToken(cfg.Token)
+			}
+			return nil, nil
+		}, nil
+	case config.VaultAuthMethodAppRole:
+		return func(ctx context.Context, client *vault.Client) (*vault.SecretAuth, error) {
+			// Read the secret ID for each login, so that a rotated one is used.
+			secretID, err := vaultSecretID(cfg, baseDir)
+			if err != nil {
+				return nil, err
+			}
+			return vaultLoginWithData(ctx, client, mountPath, map[string]any{
+				"role_id":   cfg.RoleID,
+				"secret_id": secretID,
+			})
+		}, nil
+	case config.VaultAuthMethodKubernetes:
+		return func(ctx context.Context, client *vault.Client) (*vault.SecretAuth, error) {
+			tokenPath := cfg.ServiceAccountTokenPath
+			if len(tokenPath) == 0 {
+				tokenPath = config.DefaultVaultServiceAccountTokenPath
+			}
+			jwt, err := os.ReadFile(tokenPath)
+			if err != nil {
+				return nil, err
+			}
+			return vaultLoginWithData(ctx, client, mountPath, map[string]any{
+				"role": cfg.Role,
+				"jwt":  strings.TrimSpace(string(jwt)),
+			})
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid vault auth method: %s", method)
+	}
+}
+
+// vaultSecretID returns the AppRole secret ID from SecretID, the environment variable SecretIDEnv
+// or the file SecretIDPath, in this order. If none of them is set, it returns empty, which is
+// valid for a role without bind_secret_id.
+func vaultSecretID(cfg config.VaultAuthConfig, baseDir string) (string, error) {
+	switch {
+	case len(cfg.SecretID) != 0:
+		return cfg.SecretID, nil
+	case len(cfg.SecretIDEnv) != 0:
+		secretID := os.Getenv(cfg.SecretIDEnv)
+		if len(secretID) == 0 {
+			return "", fmt.Errorf("environment variable '%s' of secret ID is empty", cfg.SecretIDEnv)
+		}
+		return secretID, nil
+	case len(cfg.SecretIDPath) != 0:
+		b, err := os.ReadFile(localfile.ResolvePath(baseDir, cfg.SecretIDPath))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	default:
+		return "", nil
+	}
+}
+
+func vaultLoginWithData(ctx context.Context, client *vault.Client, mountPath string, data map[string]any) (*vault.SecretAuth, error) {
+	secret, err := client.Logical().WriteWithContext(ctx, "auth/"+mountPath+"/login", data)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Auth == nil {
+		return nil, fmt.Errorf("no auth info returned")
+	}
+
+	client.SetToken(secret.Auth.ClientToken)
+
+	return secret.Auth, nil
+}
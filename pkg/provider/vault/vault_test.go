@@ -0,0 +1,616 @@
+/**
+ * Copyright 2026 DWANGO Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dwango/yashiro/internal/client/cache"
+	"github.com/dwango/yashiro/internal/values"
+	"github.com/dwango/yashiro/pkg/config"
+	"github.com/dwango/yashiro/pkg/provider"
+	vault "github.com/hashicorp/vault/api"
+)
+
+func Test_newVaultClient(t *testing.T) {
+	type args struct {
+		cfg *config.Config
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    provider.Provider
+		wantErr bool
+	}{
+		{
+			name: "ok: vault is not configured",
+			args: args{
+				cfg: &config.Config{},
+			},
+			want: nil,
+		},
+		{
+			name: "error: invalid auth method",
+			args: args{
+				cfg: &config.Config{Vault: &config.VaultConfig{
+					Auth: config.VaultAuthConfig{Method: "unknown"},
+				}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newVaultClient(tt.args.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newVaultClient() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newVaultClient() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type mockKvClient func(ctx context.Context, v config.VaultKvValueConfig) (map[string]any, error)
+
+func (m mockKvClient) ReadSecret(ctx context.Context, v config.VaultKvValueConfig) (map[string]any, error) {
+	return m(ctx, v)
+}
+
+func Test_vaultClient_GetValues(t *testing.T) {
+	type fields struct {
+		kvClient kvClient
+		kvValue  []config.VaultKvValueConfig
+	}
+	type args struct {
+		ctx            context.Context
+		ignoreNotFound bool
+	}
+
+	dataKvClient := mockKvClient(func(ctx context.Context, v config.VaultKvValueConfig) (map[string]any, error) {
+		return map[string]any{"username": "user", "port": float64(5432)}, nil
+	})
+	notFoundKvClient := mockKvClient(func(ctx context.Context, v config.VaultKvValueConfig) (map[string]any, error) {
+		return nil, vault.ErrSecretNotFound
+	})
+
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    values.Values
+		wantErr bool
+	}{
+		{
+			name: "ok: key",
+			fields: fields{
+				kvClient: dataKvClient,
+				kvValue: []config.VaultKvValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "db", Ref: stringPtr("username")}, Key: "username"},
+					{ValueConfig: config.ValueConfig{Name: "db", Ref: stringPtr("port")}, Key: "port"},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			want: values.Values{"username": "user", "port": "5432"},
+		},
+		{
+			name: "ok: all data as json",
+			fields: fields{
+				kvClient: dataKvClient,
+				kvValue: []config.VaultKvValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "db", IsJSON: true}},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			want: values.Values{"db": map[string]any{"username": "user", "port": float64(5432)}},
+		},
+		{
+			name: "ok: ignore not found error",
+			fields: fields{
+				kvClient: notFoundKvClient,
+				kvValue: []config.VaultKvValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "db"}},
+				},
+			},
+			args: args{
+				ctx:            context.Background(),
+				ignoreNotFound: true,
+			},
+			want: values.Values{},
+		},
+		{
+			name: "ok: ignore not found key",
+			fields: fields{
+				kvClient: dataKvClient,
+				kvValue: []config.VaultKvValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "db"}, Key: "password"},
+				},
+			},
+			args: args{
+				ctx:            context.Background(),
+				ignoreNotFound: true,
+			},
+			want: values.Values{},
+		},
+		{
+			name: "error: return not found",
+			fields: fields{
+				kvClient: notFoundKvClient,
+				kvValue: []config.VaultKvValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "db"}},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: not found key",
+			fields: fields{
+				kvClient: dataKvClient,
+				kvValue: []config.VaultKvValueConfig{
+					{ValueConfig: config.ValueConfig{Name: "db"}, Key: "password"},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := vaultClient{
+				kvClient: tt.fields.kvClient,
+				kvValue:  tt.fields.kvValue,
+			}
+			got, err := c.GetValues(tt.args.ctx, tt.args.ignoreNotFound)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("vaultClient.GetValues() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("vaultClient.GetValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_kvClientWithCache_ReadSecret(t *testing.T) {
+	dataKvClient := mockKvClient(func(ctx context.Context, v config.VaultKvValueConfig) (map[string]any, error) {
+		return map[string]any{"key": "test"}, nil
+	})
+	cachedLoadFunc := func(_ context.Context, key string, decrypt bool) (*string, bool, error) {
+		return stringPtr(`{"key":"value"}`), false, nil
+	}
+
+	type fields struct {
+		client kvClient
+		cache  cache.Cache
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name: "ok: get from cache",
+			fields: fields{
+				client: nil,
+				cache:  mockCache{load: cachedLoadFunc, save: mockSaveFunc},
+			},
+			want: map[string]any{"key": "value"},
+		},
+		{
+			name: "ok: large integer is not rounded",
+			fields: fields{
+				client: nil,
+				cache: mockCache{load: func(_ context.Context, key string, decrypt bool) (*string, bool, error) {
+					return stringPtr(`{"id":12345678901234567890}`), false, nil
+				}, save: mockSaveFunc},
+			},
+			want: map[string]any{"id": json.Number("12345678901234567890")},
+		},
+		{
+			name: "ok: get from client(cache disabled)",
+			fields: fields{
+				client: dataKvClient,
+				cache:  nil,
+			},
+			want: map[string]any{"key": "test"},
+		},
+		{
+			name: "ok: get from client(no cache)",
+			fields: fields{
+				client: dataKvClient,
+				cache:  mockCache{load: mockLoadFuncNotFound, save: mockSaveFunc},
+			},
+			want: map[string]any{"key": "test"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := kvClientWithCache{
+				client: tt.fields.client,
+				cache:  tt.fields.cache,
+			}
+			got, err := c.ReadSecret(context.Background(), config.VaultKvValueConfig{ValueConfig: config.ValueConfig{Name: "any"}})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("kvClientWithCache.ReadSecret() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kvClientWithCache.ReadSecret() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeVaultServer serves AppRole login, KV version 1 at "kv" and KV version 2 at "secret". Each
+// login issues a new token, and only the latest one is accepted.
+type fakeVaultServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	logins int
+	token  string
+}
+
+// revoke makes the current token rejected.
+func (s *fakeVaultServer) revoke() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.token = ""
+}
+
+func (s *fakeVaultServer) loginCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.logins
+}
+
+func (s *fakeVaultServer) authorized(r *http.Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.token) != 0 && r.Header.Get("X-Vault-Token") == s.token
+}
+
+func newFakeVaultServer(t *testing.T) *fakeVaultServer {
+	t.Helper()
+
+	writeJSON := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	s := &fakeVaultServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/auth/approle/login", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["role_id"] != "role" || body["secret_id"] != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]any{"errors": []string{"invalid role or secret"}})
+			return
+		}
+
+		s.mu.Lock()
+		s.logins++
+		s.token = fmt.Sprintf("s.token-%d", s.logins)
+		token := s.token
+		s.mu.Unlock()
+
+		writeJSON(w, map[string]any{"auth": map[string]any{"client_token": token, "lease_duration": 3600}})
+	})
+	mux.HandleFunc("/v1/kv/app", func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		writeJSON(w, map[string]any{"data": map[string]any{"key": "v1"}})
+	})
+	mux.HandleFunc("/v1/secret/data/app", func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		version := r.URL.Query().Get("version")
+		if len(version) == 0 {
+			version = "2"
+		}
+		writeJSON(w, map[string]any{"data": map[string]any{
+			"data":     map[string]any{"key": "v2-" + version},
+			"metadata": map[string]any{"version": 2},
+		}})
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// newFakeVaultKvClient returns a client of srv without a token.
+func newFakeVaultKvClient(t *testing.T, srv *fakeVaultServer, login vaultLogin) *vaultKvClient {
+	t.Helper()
+
+	cfg := vault.DefaultConfig()
+	cfg.Address = srv.URL
+	cli, err := vault.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("vault.NewClient() error = %v", err)
+	}
+	cli.ClearToken()
+
+	return &vaultKvClient{client: cli, login: login}
+}
+
+func Test_vaultKvClient_ReadSecret(t *testing.T) {
+	srv := newFakeVaultServer(t)
+
+	login, err := newVaultLogin(config.VaultAuthConfig{
+		Method:   config.VaultAuthMethodAppRole,
+		RoleID:   "role",
+		SecretID: "secret",
+	}, "")
+	if err != nil {
+		t.Fatalf("newVaultLogin() error = %v", err)
+	}
+	invalidLogin, err := newVaultLogin(config.VaultAuthConfig{
+		Method:   config.VaultAuthMethodAppRole,
+		RoleID:   "role",
+		SecretID: "invalid",
+	}, "")
+	if err != nil {
+		t.Fatalf("newVaultLogin() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		login   vaultLogin
+		v       config.VaultKvValueConfig
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name:  "ok: kv version 1",
+			login: login,
+			v: config.VaultKvValueConfig{
+				ValueConfig: config.ValueConfig{Name: "app"}, Mount: "kv", EngineVersion: 1,
+			},
+			want: map[string]any{"key": "v1"},
+		},
+		{
+			name:  "ok: kv version 2",
+			login: login,
+			v: config.VaultKvValueConfig{
+				ValueConfig: config.ValueConfig{Name: "app"},
+			},
+			want: map[string]any{"key": "v2-2"},
+		},
+		{
+			name:  "ok: kv version 2 with version",
+			login: login,
+			v: config.VaultKvValueConfig{
+				ValueConfig: config.ValueConfig{Name: "app"}, Version: 1,
+			},
+			want: map[string]any{"key": "v2-1"},
+		},
+		{
+			name:  "error: version with kv version 1",
+			login: login,
+			v: config.VaultKvValueConfig{
+				ValueConfig: config.ValueConfig{Name: "app"}, Mount: "kv", EngineVersion: 1, Version: 1,
+			},
+			wantErr: true,
+		},
+		{
+			name:  "error: failed to login",
+			login: invalidLogin,
+			v: config.VaultKvValueConfig{
+				ValueConfig: config.ValueConfig{Name: "app"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeVaultKvClient(t, srv, tt.login)
+			got, err := c.ReadSecret(context.Background(), tt.v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("vaultKvClient.ReadSecret() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("vaultKvClient.ReadSecret() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_vaultKvClient_ReadSecret_relogin(t *testing.T) {
+	login, err := newVaultLogin(config.VaultAuthConfig{
+		Method:   config.VaultAuthMethodAppRole,
+		RoleID:   "role",
+		SecretID: "secret",
+	}, "")
+	if err != nil {
+		t.Fatalf("newVaultLogin() error = %v", err)
+	}
+	v := config.VaultKvValueConfig{ValueConfig: config.ValueConfig{Name: "app"}}
+
+	tests := []struct {
+		name   string
+		modify func(srv *fakeVaultServer, c *vaultKvClient)
+		want   int
+	}{
+		{
+			name:   "ok: token is reused",
+			modify: func(srv *fakeVaultServer, c *vaultKvClient) {},
+			want:   1,
+		},
+		{
+			name: "ok: token is about to expire",
+			modify: func(srv *fakeVaultServer, c *vaultKvClient) {
+				c.expiresAt = time.Now().Add(-time.Second)
+			},
+			want: 2,
+		},
+		{
+			name: "ok: token is revoked",
+			modify: func(srv *fakeVaultServer, c *vaultKvClient) {
+				srv.revoke()
+			},
+			want: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeVaultServer(t)
+			c := newFakeVaultKvClient(t, srv, login)
+
+			if _, err := c.ReadSecret(context.Background(), v); err != nil {
+				t.Fatalf("vaultKvClient.ReadSecret() error = %v", err)
+			}
+			if c.expiresAt.IsZero() {
+				t.Errorf("vaultKvClient.expiresAt is zero, want the expiration of the token")
+			}
+			tt.modify(srv, c)
+
+			got, err := c.ReadSecret(context.Background(), v)
+			if err != nil {
+				t.Errorf("vaultKvClient.ReadSecret() error = %v", err)
+				return
+			}
+			if want := map[string]any{"key": "v2-2"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("vaultKvClient.ReadSecret() = %v, want %v", got, want)
+			}
+			if logins := srv.loginCount(); logins != tt.want {
+				t.Errorf("login count = %v, want %v", logins, tt.want)
+			}
+		})
+	}
+}
+
+func Test_vaultSecretID(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "secret-id"), []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_VAULT_SECRET_ID", "from-env")
+
+	tests := []struct {
+		name    string
+		cfg     config.VaultAuthConfig
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "ok: secret ID",
+			cfg:  config.VaultAuthConfig{SecretID: "secret", SecretIDEnv: "TEST_VAULT_SECRET_ID"},
+			want: "secret",
+		},
+		{
+			name: "ok: environment variable",
+			cfg:  config.VaultAuthConfig{SecretIDEnv: "TEST_VAULT_SECRET_ID"},
+			want: "from-env",
+		},
+		{
+			name: "ok: file relative to the base directory",
+			cfg:  config.VaultAuthConfig{SecretIDPath: "secret-id"},
+			want: "from-file",
+		},
+		{
+			name: "ok: not set",
+			cfg:  config.VaultAuthConfig{},
+			want: "",
+		},
+		{
+			name:    "error: environment variable is empty",
+			cfg:     config.VaultAuthConfig{SecretIDEnv: "TEST_VAULT_SECRET_ID_NOT_SET"},
+			wantErr: true,
+		},
+		{
+			name:    "error: file not found",
+			cfg:     config.VaultAuthConfig{SecretIDPath: "not-found"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := vaultSecretID(tt.cfg, dir)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("vaultSecretID() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("vaultSecretID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+var (
+	mockLoadFuncNotFound = func(_ context.Context, key string, decrypt bool) (*string, bool, error) {
+		return nil, true, nil
+	}
+	mockSaveFunc = func(_ context.Context, key string, value *string, encrypt bool) error {
+		return nil
+	}
+)
+
+type mockCache struct {
+	load func(ctx context.Context, key string, decrypt bool) (*string, bool, error)
+	save func(ctx context.Context, key string, value *string, encrypt bool) error
+}
+
+func (m mockCache) Load(ctx context.Context, key string, decrypt bool) (*string, bool, error) {
+	return m.load(ctx, key, decrypt)
+}
+
+func (m mockCache) LoadStale(_ context.Context, _ string, _ bool) (*string, error) {
+	return nil, nil
+}
+
+func (m mockCache) Save(ctx context.Context, key string, value *string, encrypt bool) error {
+	return m.save(ctx, key, value, encrypt)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}